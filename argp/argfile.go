@@ -0,0 +1,113 @@
+package argp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func splitArgLine(line string) ([]string, bool) {
+	args := []string{}
+	arg, inArg := "", false
+	quote := rune(0)
+	escaped := false
+	for _, char := range line {
+		switch {
+		case escaped:
+			arg, inArg, escaped = arg+string(char), true, false
+
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+			} else {
+				arg += string(char)
+			}
+
+		case quote == '"':
+			if char == '"' {
+				quote = 0
+			} else if char == '\\' {
+				escaped = true
+			} else {
+				arg += string(char)
+			}
+
+		case char == '\'' || char == '"':
+			quote, inArg = char, true
+
+		case char == '\\':
+			escaped = true
+
+		case char == ' ' || char == '\t':
+			if inArg {
+				args = append(args, arg)
+			}
+			arg, inArg = "", false
+
+		case char == '#' && !inArg:
+			return args, true
+
+		default:
+			arg, inArg = arg+string(char), true
+		}
+	}
+	if quote != 0 || escaped {
+		return args, false
+	}
+	if inArg {
+		args = append(args, arg)
+	}
+	return args, true
+}
+
+func readArgFile(file string, stack []string) ([]string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return []string{}, &ParseError{Err: Errors.InvalidArgFile, Token: "@" + file, Detail: err.Error()}
+	}
+	if slices.Contains(stack, abs) {
+		return []string{}, &ParseError{Err: Errors.InvalidArgFile, Token: "@" + file, Detail: "includes itself"}
+	}
+	bytes, err := os.ReadFile(abs)
+	if err != nil {
+		return []string{}, &ParseError{Err: Errors.InvalidArgFile, Token: "@" + file, Detail: err.Error()}
+	}
+
+	args := []string{}
+	for i, line := range strings.Split(strings.ReplaceAll(string(bytes), "\r\n", "\n"), "\n") {
+		lineArgs, ok := splitArgLine(line)
+		if !ok {
+			return []string{}, &ParseError{Err: Errors.InvalidArgFile, Token: "@" + file, Detail: "unterminated quote on line " + strconv.Itoa(i+1)}
+		}
+		args = append(args, lineArgs...)
+	}
+	return expandArgFiles(args, filepath.Dir(abs), append(slices.Clone(stack), abs))
+}
+
+func expandArgFiles(args []string, dir string, stack []string) ([]string, error) {
+	expanded := []string{}
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		if !strings.HasPrefix(arg, "@") || len(arg) == 1 {
+			expanded = append(expanded, arg)
+			continue
+		} else if strings.HasPrefix(arg, "@@") {
+			expanded = append(expanded, arg[1:])
+			continue
+		}
+		file := arg[1:]
+		if !filepath.IsAbs(file) && dir != "" {
+			file = filepath.Join(dir, file)
+		}
+		fileArgs, err := readArgFile(file, stack)
+		if err != nil {
+			return []string{}, err
+		}
+		expanded = append(expanded, fileArgs...)
+	}
+	return expanded, nil
+}
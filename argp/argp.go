@@ -1,65 +1,469 @@
 package argp
 
 import (
+	"bufio"
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/term"
 )
 
-func expandArgs[T any](s *T, args []string) []string {
-	allSwitches := []string{}
-	allPrefixes := []string{}
-	forEachStructField(s, func(field reflect.StructField, value reflect.Value) {
-		if field.Type.String() == "[]string" {
-			return
+type (
+	// Error returned by `argp.ParseE`, wraps one of `argp.Errors`.
+	ParseError struct {
+		// One of `argp.Errors`.
+		Err error
+		// Name of the struct field related to the error, may be empty.
+		Field string
+		// Switch related to the error, may be empty.
+		Switch string
+		// Offending argument or value, may be empty.
+		Token string
+		// Additional details such as the violated constraint, may be empty.
+		Detail string
+		// Known switches or subcommands similar to Token, set for `argp.Errors.UnknownArgument`.
+		Suggestions []string
+		// Command path the error occurred in, empty for the top level.
+		Cmd []string
+	}
+
+	// Result returned by `argp.ParseResult`.
+	Result struct {
+		// Selected subcommand path, for example `[]string{"device", "on"}`, empty for the top level.
+		//
+		// When an error is returned this is the deepest command reached.
+		Cmd []string
+		// Used switches of fields with a deprecated tag, wraps `argp.Errors.Deprecated` with the tag message as Detail.
+		Deprecated []*ParseError
+
+		loaded map[string]bool
+	}
+
+	// Defaults loaded from a config file by `argp.LoadDefaults`, parse args with `Defaults.Parse`, `Defaults.ParseE` or `Defaults.ParseResult`.
+	Defaults[T any] struct {
+		// Struct with the loaded values.
+		Value T
+		// Fields present in the config file keyed by subcommand path and field name, for example "device.IP".
+		loaded map[string]bool
+	}
+)
+
+var (
+	// Argp specific errors, errors returned by `argp.ParseE` are wrapped in a `argp.ParseError`.
+	Errors = struct {
+		UnknownArgument, MissingRequired, MissingValue, InvalidValue, HelpRequested, CompletionRequested, Deprecated,
+		NotAChoice, OutOfRange, PatternMismatch, Excluded, MissingOneOf, InvalidArgFile,
+		NotAStruct, MissingSwitch, UnsupportedType, UnsupportedShell, InvalidTag error
+	}{
+		UnknownArgument:     errors.New("unknown argument"),
+		MissingRequired:     errors.New("missing required argument"),
+		MissingValue:        errors.New("missing argument value for"),
+		InvalidValue:        errors.New("invalid value"),
+		HelpRequested:       errors.New("help requested"),
+		CompletionRequested: errors.New("completion requested"),
+		Deprecated:          errors.New("deprecated switch"),
+		NotAChoice:          errors.New("invalid choice for"),
+		OutOfRange:          errors.New("value out of range for"),
+		PatternMismatch:     errors.New("value does not match pattern for"),
+		Excluded:            errors.New("conflicting arguments"),
+		MissingOneOf:        errors.New("missing one of"),
+		InvalidArgFile:      errors.New("invalid argument file"),
+		NotAStruct:          errors.New("s is not a struct"),
+		MissingSwitch:       errors.New("no switch specified for field"),
+		UnsupportedType:     errors.New("unsupported type"),
+		UnsupportedShell:    errors.New("unsupported shell"),
+		InvalidTag:          errors.New("invalid tag for field"),
+	}
+)
+
+func (err *ParseError) Error() string {
+	msg := err.Err.Error()
+	if len(err.Cmd) > 0 {
+		msg = strings.Join(err.Cmd, " ") + ": " + msg
+	}
+	if err.Switch != "" {
+		msg += " " + err.Switch
+	} else if err.Field != "" {
+		msg += " " + err.Field
+	}
+	if err.Token != "" {
+		msg += " '" + err.Token + "'"
+	}
+	if err.Detail != "" {
+		msg += ", " + err.Detail
+	}
+	return msg
+}
+
+func (err *ParseError) Unwrap() error { return err.Err }
+
+func setErrCmd(err error, cmd []string) error {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range errs.Unwrap() {
+			_ = setErrCmd(e, cmd)
+		}
+	} else if pErr, ok := err.(*ParseError); ok && len(pErr.Cmd) == 0 {
+		pErr.Cmd = cmd
+	}
+	return err
+}
+
+func splitCmd(v reflect.Value, args []string) ([]string, string, []string, error) {
+	cmds := []string{}
+	valueSwitches := []string{}
+	err := forEachCmdField(v, func(field reflect.StructField, _ reflect.Value) error {
+		cmds = append(cmds, field.Tag.Get("cmd"))
+		return nil
+	})
+	if err != nil || len(cmds) == 0 {
+		return args, "", []string{}, err
+	}
+	err = forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isRest(field) || isFlag(field) {
+			return nil
 		}
 		prefix := field.Tag.Get("prefix")
 		if prefix == "" {
 			prefix = "-"
 		}
-		if !slices.Contains(allPrefixes, prefix) {
-			allPrefixes = append(allPrefixes, prefix)
-		}
 		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
-			allSwitches = append(allSwitches, prefix+swt)
+			valueSwitches = append(valueSwitches, prefix+swt)
 		}
+		return nil
 	})
+	if err != nil {
+		return args, "", []string{}, err
+	}
 
 	for i := 0; i < len(args); i++ {
-		if strings.Contains(args[i], "=") || slices.Contains(allSwitches, args[i]) {
+		if args[i] == "--" {
+			break
+		} else if slices.Contains(valueSwitches, args[i]) {
+			i++
 			continue
 		}
+		if slices.Contains(cmds, args[i]) {
+			return slices.Clone(args[:i]), args[i], slices.Clone(args[i+1:]), nil
+		}
+	}
+	return args, "", []string{}, nil
+}
 
-		prefix := ""
-		for _, item := range allPrefixes {
-			if strings.HasPrefix(args[i], item) {
-				prefix = item
-				break
+// Returns `argp.Errors.CompletionRequested` if args of a subcommand contain a switch of a completion field of v, so completion works after a subcommand.
+func completionArg(v reflect.Value, args []string) error {
+	return forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if !slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "completion") {
+			return nil
+		}
+		prefix := field.Tag.Get("prefix")
+		if prefix == "" {
+			prefix = "-"
+		}
+		for i, arg := range args {
+			if arg == "--" {
+				return nil
+			}
+			for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+				if val, ok := strings.CutPrefix(arg, prefix+swt+"="); ok {
+					return &ParseError{Err: Errors.CompletionRequested, Field: field.Name, Token: val}
+				} else if arg == prefix+swt && i+1 < len(args) {
+					return &ParseError{Err: Errors.CompletionRequested, Field: field.Name, Token: args[i+1]}
+				} else if arg == prefix+swt {
+					return &ParseError{Err: Errors.MissingValue, Field: field.Name}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func parseCmd(v reflect.Value, args []string, cmd []string, res *Result) error {
+	res.Cmd = cmd
+	args, name, cmdArgs, err := splitCmd(v, args)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if err := completionArg(v, cmdArgs); err != nil {
+			return setErrCmd(err, cmd)
+		}
+	}
+	args, rest, err := tokenizeArgs(v, args)
+	if err != nil {
+		return setErrCmd(err, cmd)
+	}
+	deprecated := len(res.Deprecated)
+	args, err = parseArgs(v, args, rest, res)
+	for _, pErr := range res.Deprecated[deprecated:] {
+		pErr.Cmd = cmd
+	}
+	if errors.Is(err, Errors.HelpRequested) || errors.Is(err, Errors.CompletionRequested) || errors.Is(err, Errors.NotAStruct) || errors.Is(err, Errors.MissingSwitch) || errors.Is(err, Errors.UnsupportedType) || errors.Is(err, Errors.InvalidTag) {
+		return setErrCmd(err, cmd)
+	}
+
+	errs := []error{}
+	if err != nil {
+		errs = append(errs, err)
+		// Fields not reached before the error leave their own switches and values in args, only unknown switches are reported.
+		args, err = unknownSwitches(v, args)
+		if err != nil {
+			return setErrCmd(err, cmd)
+		}
+	}
+	if len(args) > 0 {
+		known, err := knownArgs(v)
+		if err != nil {
+			return setErrCmd(err, cmd)
+		}
+		for _, arg := range args {
+			if strings.Contains(arg, "=") {
+				arg = strings.Split(arg, "=")[0]
+			}
+			pErr := &ParseError{Err: Errors.UnknownArgument, Token: arg, Suggestions: suggest(arg, known)}
+			if len(pErr.Suggestions) > 0 {
+				pErr.Detail = "did you mean " + strings.Join(pErr.Suggestions, ", ") + "?"
+			}
+			errs = append(errs, pErr)
+		}
+	}
+
+	cmdErr := forEachCmdField(v, func(field reflect.StructField, value reflect.Value) error {
+		if name == "" {
+			if value.Kind() == reflect.Pointer {
+				value.SetZero()
 			}
+			if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "required") {
+				return &ParseError{Err: Errors.MissingRequired, Field: field.Name}
+			}
+			return nil
+		}
+		if field.Tag.Get("cmd") != name {
+			if value.Kind() == reflect.Pointer {
+				value.SetZero()
+			}
+			return nil
+		}
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(field.Type.Elem()))
+			}
+			value = value.Elem()
+		}
+		return parseCmd(value, cmdArgs, append(slices.Clone(cmd), name), res)
+	})
+	if errors.Is(cmdErr, Errors.HelpRequested) || errors.Is(cmdErr, Errors.CompletionRequested) {
+		return cmdErr
+	} else if cmdErr != nil {
+		errs = append(errs, cmdErr)
+	}
+
+	return setErrCmd(errors.Join(errs...), cmd)
+}
+
+// Returns the args that start with a prefix but are not a switch of any field of v, including hidden fields.
+func unknownSwitches(v reflect.Value, args []string) ([]string, error) {
+	allPrefixes, switches := []string{}, []string{}
+	err := forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isRest(field) {
+			return nil
+		}
+		prefix := field.Tag.Get("prefix")
+		if prefix == "" {
+			prefix = "-"
+		}
+		if !slices.Contains(allPrefixes, prefix) {
+			allPrefixes = append(allPrefixes, prefix)
+		}
+		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+			switches = append(switches, prefix+swt)
+		}
+		return nil
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	return slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+		return slices.Contains(switches, strings.SplitN(arg, "=", 2)[0]) || !slices.ContainsFunc(allPrefixes, func(p string) bool { return strings.HasPrefix(arg, p) && len(arg) > len(p) })
+	}), nil
+}
+
+func knownArgs(v reflect.Value) ([]string, error) {
+	known := []string{}
+	err := forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isRest(field) || isHidden(field) {
+			return nil
 		}
+		prefix := field.Tag.Get("prefix")
 		if prefix == "" {
+			prefix = "-"
+		}
+		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+			known = append(known, prefix+swt)
+		}
+		return nil
+	})
+	if err != nil {
+		return known, err
+	}
+	err = forEachCmdField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if !isHidden(field) {
+			known = append(known, field.Tag.Get("cmd"))
+		}
+		return nil
+	})
+	return known, err
+}
+
+// Optimal string alignment distance, the Levenshtein distance extended with transpositions of adjacent characters.
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func suggest(arg string, known []string) []string {
+	best, suggestions := -1, []string{}
+	for _, k := range known {
+		dist := editDistance(arg, k)
+		if dist > len([]rune(k))/3 || (best != -1 && dist > best) {
 			continue
 		}
+		if dist != best {
+			best, suggestions = dist, []string{}
+		}
+		if !slices.Contains(suggestions, k) {
+			suggestions = append(suggestions, k)
+		}
+	}
+	return suggestions
+}
 
-		newArgs := slices.Clone(args[:i])
-		for a := range strings.SplitSeq(strings.Replace(args[i], prefix, "", 1), "") {
-			newArgs = append(newArgs, prefix+a)
+func tokenizeArgs(v reflect.Value, args []string) ([]string, []string, error) {
+	allPrefixes := []string{}
+	takesValue := map[string]bool{}
+	negations := map[string]string{}
+	err := forEachStructField(v, func(field reflect.StructField, value reflect.Value) error {
+		if isRest(field) {
+			return nil
+		}
+		prefix := field.Tag.Get("prefix")
+		if prefix == "" {
+			prefix = "-"
+		}
+		if !slices.Contains(allPrefixes, prefix) {
+			allPrefixes = append(allPrefixes, prefix)
+		}
+		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+			takesValue[prefix+swt] = !isFlag(field)
+			if field.Type.String() == "bool" {
+				lead := prefix + swt[:len(swt)-len(strings.TrimLeft(swt, prefix))]
+				negations[lead+"no-"+strings.TrimPrefix(prefix+swt, lead)] = prefix + swt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return args, []string{}, err
+	}
+
+	normalize := func(arg string) ([]string, bool) {
+		if _, ok := takesValue[strings.SplitN(arg, "=", 2)[0]]; ok {
+			return []string{arg}, true
+		} else if swt, ok := negations[arg]; ok {
+			return []string{swt + "=false"}, true
+		}
+
+		for _, prefix := range allPrefixes {
+			if !strings.HasPrefix(arg, prefix) || len(arg) <= len(prefix) {
+				continue
+			}
+			tokens := []string{}
+			cluster := strings.TrimPrefix(arg, prefix)
+			for i, char := range cluster {
+				swt := prefix + string(char)
+				value, ok := takesValue[swt]
+				if !ok {
+					tokens = []string{}
+					break
+				}
+				if !value {
+					tokens = append(tokens, swt)
+					continue
+				}
+				if remainder := cluster[i+len(string(char)):]; strings.HasPrefix(remainder, "=") {
+					tokens = append(tokens, swt+remainder)
+				} else if remainder != "" {
+					tokens = append(tokens, swt+"="+remainder)
+				} else {
+					tokens = append(tokens, swt)
+				}
+				break
+			}
+			if len(tokens) > 0 {
+				return tokens, true
+			}
 		}
-		args = append(newArgs, args[i+1:]...)
-		i = i + (len(newArgs) - 1)
+		return []string{arg}, false
 	}
 
-	return args
+	tokens := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return tokens, slices.Clone(args[i+1:]), nil
+		}
+		normalized, ok := normalize(args[i])
+		if !ok {
+			tokens = append(tokens, args[i])
+			continue
+		}
+		last := normalized[len(normalized)-1]
+		if takesValue[last] && i+1 < len(args) && args[i+1] != "--" {
+			if _, ok := normalize(args[i+1]); !ok {
+				normalized[len(normalized)-1] = last + "=" + args[i+1]
+				i++
+			}
+		}
+		tokens = append(tokens, normalized...)
+	}
+	return tokens, []string{}, nil
 }
 
-func parseArgs[T any](s *T, args []string) []string {
+func parseArgs(v reflect.Value, args []string, rest []string, res *Result) ([]string, error) {
 	allPrefixes := []string{}
-	forEachStructField(s, func(field reflect.StructField, value reflect.Value) {
-		if field.Type.String() == "[]string" {
-			return
+	err := forEachStructField(v, func(field reflect.StructField, value reflect.Value) error {
+		if isRest(field) {
+			return nil
 		}
 		prefix := field.Tag.Get("prefix")
 		if prefix == "" {
@@ -68,43 +472,69 @@ func parseArgs[T any](s *T, args []string) []string {
 		if !slices.Contains(allPrefixes, prefix) {
 			allPrefixes = append(allPrefixes, prefix)
 		}
+		return nil
 	})
+	if err != nil {
+		return args, err
+	}
 
-	forEachStructField(s, func(field reflect.StructField, value reflect.Value) {
-		if field.Type.String() == "[]string" {
-			return
+	switches, given, preset := map[string]string{}, map[string]string{}, map[string]bool{}
+	err = forEachStructField(v, func(field reflect.StructField, value reflect.Value) error {
+		if isRest(field) {
+			return nil
 		}
 
 		prefix := field.Tag.Get("prefix")
 		if prefix == "" {
 			prefix = "-"
 		}
+		switches[field.Name] = prefix + strings.Split(field.Tag.Get("switch"), ",")[0]
 
-		index := -1
-		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
-			index = slices.IndexFunc(args, func(a string) bool { return a == prefix+swt || strings.HasPrefix(a, prefix+swt+"=") })
-			if index > -1 {
-				if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "help") {
-					HelpMenu(*s, true)
-					os.Exit(0)
+		multi := isMulti(field.Type)
+		count := isCount(field)
+		occurrences := 0
+		vals := []string{}
+		for {
+			index := -1
+			for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+				index = slices.IndexFunc(args, func(a string) bool { return a == prefix+swt || strings.HasPrefix(a, prefix+swt+"=") })
+				if index > -1 {
+					if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "help") {
+						return &ParseError{Err: Errors.HelpRequested, Field: field.Name, Token: args[index]}
+					}
+					break
 				}
+			}
+			if index == -1 {
 				break
 			}
-		}
+			given[field.Name] = strings.SplitN(args[index], "=", 2)[0]
+			if field.Tag.Get("deprecated") != "" {
+				res.Deprecated = append(res.Deprecated, &ParseError{Err: Errors.Deprecated, Field: field.Name, Switch: given[field.Name], Detail: field.Tag.Get("deprecated")})
+			}
 
-		val := field.Tag.Get("default")
-		if index > -1 {
+			val := field.Tag.Get("default")
 			if strings.Contains(args[index], "=") {
-				val = strings.Split(args[index], "=")[1]
+				val = strings.SplitN(args[index], "=", 2)[1]
+				occurrences, _ = strconv.Atoi(val)
 			} else if field.Type.String() == "bool" {
 				val = "true"
-			} else if index+1 < len(args) && !slices.ContainsFunc(allPrefixes, func(item string) bool { return strings.HasPrefix(args[index+1], item) }) {
-				val = args[index+1]
-				args = slices.Delete(args, index, index+1)
+			} else if count {
+				occurrences++
+				val = strconv.Itoa(occurrences)
 			}
 			args = slices.Delete(args, index, index+1)
 
-		} else if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "posistional") {
+			if val == "" {
+				return &ParseError{Err: Errors.MissingValue, Field: field.Name}
+			}
+			if !multi {
+				vals = []string{}
+			}
+			vals = append(vals, val)
+		}
+
+		if len(vals) == 0 && slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "posistional") {
 			i := slices.IndexFunc(args, func(a string) bool {
 				for _, p := range allPrefixes {
 					if strings.HasPrefix(a, p) {
@@ -114,180 +544,436 @@ func parseArgs[T any](s *T, args []string) []string {
 				return true
 			})
 			if i > -1 {
-				val = args[i]
+				given[field.Name] = field.Name
+				vals = append(vals, args[i])
 				args = slices.Delete(args, i, i+1)
+			} else if len(rest) > 0 {
+				given[field.Name] = field.Name
+				vals = append(vals, rest[0])
+				rest = rest[1:]
 			}
 		}
 
-		if val == "" && field.Type.String() != "bool" {
-			if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "required") {
-				fmt.Println("Missing required argument " + field.Name)
-				HelpMenu(*s, false)
-				os.Exit(0)
-			} else if index > -1 {
-				fmt.Println("Missing argument value for " + field.Name)
-				HelpMenu(*s, false)
-				os.Exit(0)
+		if len(vals) == 0 {
+			val := field.Tag.Get("default")
+			if env := field.Tag.Get("env"); env != "" && os.Getenv(env) != "" {
+				given[field.Name] = "$" + env
+				val = os.Getenv(env)
+			} else if !value.IsZero() || res.loaded[loadedKey(res.Cmd, field.Name)] {
+				preset[field.Name] = true
+				return nil
 			}
-			return
-		}
-
-		switch field.Type.String() {
-		case "string":
-			value.SetString(val)
-
-		case "bool":
-			value.SetBool(val == "true")
-
-		case "int", "int8", "int16", "int32", "int64":
-			v, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				fmt.Println("Invalid int format '" + val + "'")
-				HelpMenu(*s, false)
-				os.Exit(0)
+			if val == "" && slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "prompt") && term.IsTerminal(int(os.Stdin.Fd())) {
+				var err error
+				val, err = promptValue(field)
+				if err != nil {
+					return &ParseError{Err: err, Field: field.Name}
+				} else if val != "" {
+					given[field.Name] = field.Name
+				}
 			}
-			value.SetInt(v)
 
-		case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
-			v, err := strconv.ParseUint(val, 10, 64)
-			if err != nil {
-				fmt.Println("Invalid uint format '" + val + "'")
-				HelpMenu(*s, false)
-				os.Exit(0)
+			if val == "" {
+				if field.Type.String() != "bool" && slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "required") {
+					return &ParseError{Err: Errors.MissingRequired, Field: field.Name}
+				}
+				return nil
+			}
+			vals = []string{val}
+			if multi {
+				vals = strings.Split(val, ",")
 			}
-			value.SetUint(v)
+		}
 
-		case "float32", "float64":
-			v, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				fmt.Println("Invalid float format '" + val + "'")
-				HelpMenu(*s, false)
-				os.Exit(0)
+		if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "completion") {
+			return &ParseError{Err: Errors.CompletionRequested, Field: field.Name, Token: vals[0]}
+		}
+		if multi {
+			value.SetZero()
+		}
+		for _, val := range vals {
+			if err := setValue(value, val, field.Tag.Get("layout")); errors.Is(err, Errors.UnsupportedType) {
+				return &ParseError{Err: err, Field: field.Name, Token: field.Type.String()}
+			} else if err != nil {
+				return &ParseError{Err: err, Field: field.Name, Token: val}
+			}
+		}
+		for _, val := range vals {
+			swt := switches[field.Name]
+			if given[field.Name] != "" {
+				swt = given[field.Name]
+			}
+			if err := validateValue(field, val, swt); err != nil {
+				return err
 			}
-			value.SetFloat(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return args, err
+	}
 
-		default:
-			panic("unsuported type " + field.Type.String())
+	groups, groupNames := map[string][]string{}, []string{}
+	err = forEachStructField(v, func(field reflect.StructField, value reflect.Value) error {
+		if group := field.Tag.Get("oneof"); group != "" {
+			if _, ok := groups[group]; !ok {
+				groupNames = append(groupNames, group)
+			}
+			groups[group] = append(groups[group], field.Name)
+		}
+		if field.Tag.Get("excludes") == "" {
+			return nil
 		}
+		for name := range strings.SplitSeq(field.Tag.Get("excludes"), ",") {
+			if _, ok := switches[name]; !ok {
+				return &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: name}
+			}
+			if given[field.Name] != "" && given[name] != "" {
+				return &ParseError{Err: Errors.Excluded, Field: field.Name, Switch: given[field.Name], Detail: "cannot be used with " + given[name]}
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		return args, err
+	}
+	for _, group := range groupNames {
+		names := slices.DeleteFunc(slices.Clone(groups[group]), func(name string) bool { return given[name] == "" })
+		if len(names) > 1 {
+			return args, &ParseError{Err: Errors.Excluded, Field: names[0], Switch: given[names[0]], Detail: "cannot be used with " + given[names[1]]}
+		} else if len(names) == 0 && !slices.ContainsFunc(groups[group], func(name string) bool { return preset[name] }) {
+			swts := []string{}
+			for _, name := range groups[group] {
+				swts = append(swts, switches[name])
+			}
+			return args, &ParseError{Err: Errors.MissingOneOf, Field: group, Switch: strings.Join(swts, ", ")}
+		}
+	}
 
-	forEachStructField(s, func(field reflect.StructField, value reflect.Value) {
-		if field.Type.String() != "[]string" {
-			return
+	err = forEachStructField(v, func(field reflect.StructField, value reflect.Value) error {
+		if !isRest(field) {
+			return nil
 		}
+		args = append(args, rest...)
+		rest = []string{}
 		if len(args) == 0 && slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "required") {
-			fmt.Println("Missing required argument " + field.Name)
-			HelpMenu(*s, false)
-			os.Exit(0)
+			return &ParseError{Err: Errors.MissingRequired, Field: field.Name}
 		}
 		value.Set(reflect.ValueOf(args))
 		args = []string{}
+		return nil
 	})
 
-	return args
+	return append(args, rest...), err
 }
 
-func forEachStructField[T any](s *T, handler func(reflect.StructField, reflect.Value)) {
-	tOf := reflect.TypeOf(*s)
-	if tOf.Kind() != reflect.Struct {
-		panic("s is not a stuct")
+// Reads the value of `field` from the terminal, without echo for fields with opts secret.
+func promptValue(field reflect.StructField) (string, error) {
+	msg := field.Name
+	if field.Tag.Get("help") != "" && !slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "help") {
+		msg += " (" + field.Tag.Get("help") + ")"
 	}
-	vOf := reflect.ValueOf(s).Elem()
-	if vOf.Kind() != reflect.Struct {
-		panic("s is not a stuct")
+	if isMulti(field.Type) {
+		msg += " [comma seperated]"
 	}
+	fmt.Fprint(os.Stderr, msg+": ")
 
-	for i := range tOf.NumField() {
-		tOfField := tOf.Field(i)
-		vOfField := vOf.Field(i)
-		if !vOfField.CanSet() {
-			continue
+	if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "secret") {
+		val, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(val), err
+	}
+	val, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(os.Stderr)
+		err = nil
+	}
+	return strings.TrimRight(val, "\r\n"), err
+}
+
+func isCount(field reflect.StructField) bool {
+	return slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "count")
+}
+
+// Field switches that do not take a value, bools and counts.
+func isFlag(field reflect.StructField) bool {
+	return field.Type.String() == "bool" || isCount(field)
+}
+
+func isHidden(field reflect.StructField) bool {
+	return field.Tag.Get("hidden") == "true" || slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "completion")
+}
+
+func isRest(field reflect.StructField) bool {
+	return field.Type.String() == "[]string" && field.Tag.Get("switch") == ""
+}
+
+func isMulti(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return false
+	}
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Map
+}
+
+func setValue(value reflect.Value, val string, layout string) error {
+	if value.Kind() == reflect.Slice && isMulti(value.Type()) {
+		elem := reflect.New(value.Type().Elem()).Elem()
+		if err := setValue(elem, val, layout); err != nil {
+			return err
+		}
+		value.Set(reflect.Append(value, elem))
+		return nil
+	} else if value.Kind() == reflect.Map && isMulti(value.Type()) {
+		k, v, ok := strings.Cut(val, "=")
+		if !ok {
+			return Errors.InvalidValue
+		}
+		key, elem := reflect.New(value.Type().Key()).Elem(), reflect.New(value.Type().Elem()).Elem()
+		if err := setValue(key, k, layout); err != nil {
+			return err
+		}
+		if err := setValue(elem, v, layout); err != nil {
+			return err
 		}
-		if tOfField.Tag.Get("switch") == "" && tOfField.Type.String() != "[]string" {
-			panic("no switch specified for field " + tOfField.Name)
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
 		}
-		handler(tOfField, vOfField)
+		value.SetMapIndex(key, elem)
+		return nil
 	}
-}
 
-// Shows help menu related to `s`. Panics if `s` is not of type struct or a public field doesn't contain a switch tag. Private struct fields are ignored.
-func HelpMenu[T any](s T, details bool) {
-	maxLenSwts := 0
-	forEachStructField(&s, func(field reflect.StructField, _ reflect.Value) {
-		if field.Type.String() == "[]string" {
-			return
+	switch value.Type().String() {
+	case "time.Duration":
+		v, err := time.ParseDuration(val)
+		if err != nil {
+			return Errors.InvalidValue
 		}
-		lenSwt := len(field.Tag.Get("switch")) + max(1, len(field.Tag.Get("prefix"))) + (len(strings.Split(field.Tag.Get("switch"), ",")))
-		if lenSwt > maxLenSwts {
-			maxLenSwts = lenSwt
+		value.SetInt(int64(v))
+		return nil
+
+	case "time.Time":
+		if layout == "" {
+			layout = time.RFC3339
 		}
-	})
+		v, err := time.Parse(layout, val)
+		if err != nil {
+			return Errors.InvalidValue
+		}
+		value.Set(reflect.ValueOf(v))
+		return nil
 
-	mainHelpMsg := ""
-	helpMenuStr := ""
-	argsStr := ""
-	forEachStructField(&s, func(field reflect.StructField, _ reflect.Value) {
-		prefix := field.Tag.Get("prefix")
-		if prefix == "" {
-			prefix = "-"
+	case "url.URL":
+		v, err := url.Parse(val)
+		if err != nil {
+			return Errors.InvalidValue
 		}
+		value.Set(reflect.ValueOf(*v))
+		return nil
+	}
 
-		swts := ""
-		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
-			swts += " " + prefix + swt
+	if u, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(val)); err != nil {
+			return Errors.InvalidValue
 		}
+		return nil
+	}
 
-		switch field.Type.String() {
-		case "string":
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]"
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(val)
 
-		case "bool":
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + "]"
+	case reflect.Bool:
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return Errors.InvalidValue
+		}
+		value.SetBool(v)
 
-		case "int", "int8", "int16", "int32", "int64":
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(val, 10, value.Type().Bits())
+		if err != nil {
+			return Errors.InvalidValue
+		}
+		value.SetInt(v)
 
-		case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v, err := strconv.ParseUint(val, 10, value.Type().Bits())
+		if err != nil {
+			return Errors.InvalidValue
+		}
+		value.SetUint(v)
+
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, value.Type().Bits())
+		if err != nil {
+			return Errors.InvalidValue
+		}
+		value.SetFloat(v)
 
-		case "float32", "float64":
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]"
+	default:
+		return Errors.UnsupportedType
+	}
+	return nil
+}
 
-		case "[]string":
-			argsStr = " [" + field.Name + "...]" + argsStr
-			swts = ""
+func compareValue(t reflect.Type, val string, limit string, layout string) (int, error) {
+	a, b := reflect.New(t).Elem(), reflect.New(t).Elem()
+	if err := setValue(a, val, layout); err != nil {
+		return 0, err
+	}
 
-		default:
-			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + "]"
+	switch a.Kind() {
+	case reflect.String:
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return 0, Errors.InvalidTag
 		}
+		return cmp.Compare(len([]rune(val)), l), nil
 
-		helpMenuStr += fmt.Sprintf("%v\r\n %-"+strconv.Itoa(maxLenSwts)+"v  %-9v", field.Name, swts, "<"+field.Type.String()+">")
-		if field.Tag.Get("opts") != "" {
-			helpMenuStr += fmt.Sprintf(" (%v)", field.Tag.Get("opts"))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := setValue(b, limit, layout); err != nil {
+			return 0, Errors.InvalidTag
 		}
-		helpMenuStr += "\r\n"
-		if field.Tag.Get("help") != "" {
-			if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "help") {
-				mainHelpMsg = fmt.Sprintf("	%v\r\n", field.Tag.Get("help"))
-				return
-			}
-			helpMenuStr += fmt.Sprintf("	%v\r\n", field.Tag.Get("help"))
+		return cmp.Compare(a.Int(), b.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if err := setValue(b, limit, layout); err != nil {
+			return 0, Errors.InvalidTag
 		}
-	})
+		return cmp.Compare(a.Uint(), b.Uint()), nil
 
-	execPath, err := os.Executable()
-	if err != nil {
-		execPath = "exec"
+	case reflect.Float32, reflect.Float64:
+		if err := setValue(b, limit, layout); err != nil {
+			return 0, Errors.InvalidTag
+		}
+		return cmp.Compare(a.Float(), b.Float()), nil
+
+	default:
+		return 0, Errors.InvalidTag
+	}
+}
+
+func validateValue(field reflect.StructField, val string, swt string) error {
+	t := field.Type
+	if isMulti(t) {
+		if t.Kind() == reflect.Map {
+			_, val, _ = strings.Cut(val, "=")
+		}
+		t = t.Elem()
+	}
+
+	if choices := field.Tag.Get("choices"); choices != "" && !slices.Contains(strings.Split(choices, ","), val) {
+		return &ParseError{Err: Errors.NotAChoice, Field: field.Name, Switch: swt, Token: val, Detail: "must be one of " + choices}
+	}
+
+	if pattern := field.Tag.Get("regex"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: pattern}
+		}
+		if !re.MatchString(val) {
+			return &ParseError{Err: Errors.PatternMismatch, Field: field.Name, Switch: swt, Token: val, Detail: "must match " + pattern}
+		}
+	}
+
+	if limit := field.Tag.Get("min"); limit != "" {
+		c, err := compareValue(t, val, limit, field.Tag.Get("layout"))
+		if errors.Is(err, Errors.InvalidTag) {
+			return &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: limit}
+		} else if err != nil {
+			return &ParseError{Err: err, Field: field.Name, Switch: swt, Token: val}
+		} else if c < 0 {
+			return &ParseError{Err: Errors.OutOfRange, Field: field.Name, Switch: swt, Token: val, Detail: "must be at least " + limit}
+		}
+	}
+
+	if limit := field.Tag.Get("max"); limit != "" {
+		c, err := compareValue(t, val, limit, field.Tag.Get("layout"))
+		if errors.Is(err, Errors.InvalidTag) {
+			return &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: limit}
+		} else if err != nil {
+			return &ParseError{Err: err, Field: field.Name, Switch: swt, Token: val}
+		} else if c > 0 {
+			return &ParseError{Err: Errors.OutOfRange, Field: field.Name, Switch: swt, Token: val, Detail: "must be at most " + limit}
+		}
+	}
+
+	return nil
+}
+
+func forEachStructField(v reflect.Value, handler func(reflect.StructField, reflect.Value) error) error {
+	if v.Kind() != reflect.Struct {
+		return &ParseError{Err: Errors.NotAStruct}
 	}
-	execPathSplit := strings.Split(strings.ReplaceAll(execPath, "\\", "/"), "/")
-	fmt.Print("Usage: " + execPathSplit[len(execPathSplit)-1] + argsStr + "\r\n")
-	if details {
-		fmt.Print(mainHelpMsg + "\r\n" + helpMenuStr)
+	tOf := v.Type()
+
+	for i := range tOf.NumField() {
+		tOfField := tOf.Field(i)
+		vOfField := v.Field(i)
+		if !vOfField.CanSet() || tOfField.Tag.Get("cmd") != "" {
+			continue
+		}
+		if tOfField.Tag.Get("switch") == "" && !isRest(tOfField) {
+			return &ParseError{Err: Errors.MissingSwitch, Field: tOfField.Name}
+		}
+		if err := handler(tOfField, vOfField); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forEachCmdField(v reflect.Value, handler func(reflect.StructField, reflect.Value) error) error {
+	if v.Kind() != reflect.Struct {
+		return &ParseError{Err: Errors.NotAStruct}
+	}
+	tOf := v.Type()
+
+	for i := range tOf.NumField() {
+		tOfField := tOf.Field(i)
+		vOfField := v.Field(i)
+		if !vOfField.CanSet() || tOfField.Tag.Get("cmd") == "" {
+			continue
+		}
+		if tOfField.Type.Kind() != reflect.Struct && (tOfField.Type.Kind() != reflect.Pointer || tOfField.Type.Elem().Kind() != reflect.Struct) {
+			return &ParseError{Err: Errors.UnsupportedType, Field: tOfField.Name, Token: tOfField.Type.String()}
+		}
+		if err := handler(tOfField, vOfField); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cmdValue(v reflect.Value, cmd []string) (reflect.Value, error) {
+	for _, name := range cmd {
+		found := false
+		err := forEachCmdField(v, func(field reflect.StructField, value reflect.Value) error {
+			if found || field.Tag.Get("cmd") != name {
+				return nil
+			}
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					value = reflect.New(field.Type.Elem())
+				}
+				value = value.Elem()
+			}
+			v, found = value, true
+			return nil
+		})
+		if err != nil {
+			return v, err
+		}
+		if !found {
+			return v, &ParseError{Err: Errors.UnknownArgument, Token: name}
+		}
 	}
+	return v, nil
 }
 
-// Parses args into s. Panics if s is not of type struct or a public field doesn't contain a switch tag. Private struct fields are ignored.
-// Runs args.HelpMenu and exits gracefully if user input is invalid.
+// Parses args into s. Private struct fields are ignored.
+// Returns s and an error wrapped in a `argp.ParseError` if user input is invalid, use `errors.Is` to check against `argp.Errors`.
+// Tags are validated before parsing using `argp.Spec`, malformed tags return `argp.Errors.InvalidTag` or `argp.Errors.UnsupportedType`.
 //
 // Struct format:
 //
@@ -296,24 +982,84 @@ func HelpMenu[T any](s T, details bool) {
 // Supported types:
 //
 //	string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64
+//	time.Duration, time.Time, net.IP, url.URL and any type implementing `encoding.TextUnmarshaler`
+//	Slices and maps of the above, see Repeatable switches.
 //
 // Available tags:
 //
-//	switch:  List of switches that can map to this field (Required).
-//	prefix:  Prefix for the switches (Default: "-").
-//	opts:    Optional parameters [posistional,required,help].
-//	default: Optional default value.
-//	help:    Help message.
+//	switch:     List of switches that can map to this field (Required).
+//	prefix:     Prefix for the switches (Default: "-").
+//	opts:       Optional parameters [posistional,required,completion,path,help,count,prompt,secret].
+//	default:    Optional default value.
+//	env:        Environment variable used as value when no switch for this field is present.
+//	layout:     Layout used to parse time.Time fields (Default: time.RFC3339).
+//	choices:    Comma seperated list of allowed values, also offered by shell completion.
+//	min:        Minimal value for numbers or minimal length for strings.
+//	max:        Maximal value for numbers or maximal length for strings.
+//	regex:      Pattern the value must match.
+//	oneof:      Name of a group of fields of which exactly one must be given.
+//	excludes:   Comma seperated list of field names that can not be given together with this field.
+//	help:       Help message.
+//	group:      Heading the field is listed under in the help menu.
+//	deprecated: Message shown as warning when a switch of this field is used, for example "use --addr instead".
+//	hidden:     Field is left out of the help menu, suggestions, shell completion and documentation when set to "true".
+//	cmd:        Name of the subcommand this field represents, replaces switch (See Subcommands).
 //
 // Opts:
 //
 //	posistional: Field will be assigned using the first argument without a switch or prefix.
 //	             Needs to be last in the struct, this is to ensure no value arguments are taken from other switches in cases " " is used to seperate key and value arguments.
 //	             As a special case the type of a posistional may be []string to populate this filed with left over arguments, in this case the tags switch, prefix and default wil be ignored and order in stuct non-important.
-//	required:    Field will be required, shows help menu if missing, if a default is given required is ignored.
-//	help:        Quick opt for help menu implementation, when a switch from this field is present `args.HelpMenu` gets called followed by `os.Exit(0)`.
-//	             Needs to be first in the struct, this is to ensure the help menu is show when a help switch is present in cases a panic is trow by other switches.
+//	required:    Field will be required, returns `argp.Errors.MissingRequired` if missing, if a default is given required is ignored.
+//	completion:  Quick opt for shell completion, when a switch from this field is present `argp.Errors.CompletionRequested` is returned with the shell as token. The switch is also recognised after a subcommand.
+//	             `argp.Parse` prints the script using `argp.GenerateCompletion` followed by `os.Exit(0)`, the field is hidden from the help menu.
+//	path:        Field is a file path, shell completion offers file paths for its value.
+//	help:        Quick opt for help menu implementation, when a switch from this field is present `argp.Errors.HelpRequested` is returned.
+//	             Needs to be first in the struct, this is to ensure the help request is returned before errors of other switches.
 //	             The help tag message provided becomes the decription of the executable.
+//	count:       Field of type int counts the occurrences of its switches, for example `-vvv` or `-v -v -v` sets 3.
+//	             A value sets the count directly, for example `-v=3`, following occurrences continue from there.
+//	prompt:      When no value is found (See Precedence) the value is asked for on the terminal, skipped when stdin is not a terminal.
+//	             Combined with required an empty answer returns `argp.Errors.MissingRequired`.
+//	secret:      Prompted values are read without echo, for passwords and tokens.
+//
+// Repeatable switches:
+//
+//	Fields of type slice or map collect every occurrence of their switches, for example `-t a -t b` populates []string{"a", "b"}.
+//	Map values are given as key=value pairs, for example `-l key=value`.
+//	Values from the env and default tags are split on ",", for example `default:"a=1,b=2"`.
+//	As a special case a []string without a switch tag is a posistional populated with left over arguments (See Opts posistional).
+//
+// Arguments:
+//
+//	-a=1 -a 1    Value of a switch, the next argument is used as value if it is not a known switch, for example `-n -5`.
+//	-abc -c1     Short switches can be combined, the first switch taking a value takes the rest of the argument as value.
+//	-vvv         Repeated short switches of a field with opts count are counted, for example to set `logger.VerboseToCLI`.
+//	--no-a       Sets a bool switch to false, the "no-" is placed after the prefix characters, for example `--no-verbose` or `-no-v`.
+//	--           Ends switch parsing, all following arguments are used as posistionals.
+//	@file        Replaced by the arguments read from file, see Argument files, "@@" escapes a literal "@".
+//
+//	When a switch of a field that is not repeatable is given multiple times the last value is used.
+//
+// Argument files:
+//
+//	Arguments starting with "@" are replaced by the arguments in the named file before any other parsing, arguments after "--" are not expanded.
+//	Arguments starting with "@@" are not expanded but passed on with one "@" removed, for example `-n @@daily` gives the value "@daily".
+//	Each line holds one or more arguments split on whitespace, quotes and backslashes work like in a shell, for example `--name "John Smith"`.
+//	Lines or words starting with "#" are comments, files may include other files, relative paths are resolved from the including file.
+//	Returns `argp.Errors.InvalidArgFile` if a file can not be read, has an unterminated quote or includes itself.
+//
+// Precedence:
+//
+//	Values are taken from the first available source: switch or posistional, env tag, value loaded by `argp.LoadDefaults` (See `argp.Defaults`) or non zero value already present in s, default tag, terminal prompt (See Opts prompt).
+//	Bools accept the values of `strconv.ParseBool` when given with "=", through env or through default.
+//
+// Subcommands:
+//
+//	A field of type struct or *struct with a cmd tag is a subcommand, the struct owns its own switches, posistionals, subcommands and help.
+//	The first argument matching a cmd tag, that is not the value of a switch, selects the subcommand, all arguments after it are parsed into the subcommand.
+//	Fields of type *struct are only allocated when their subcommand is selected, use `argp.ParseCmd` to get the selected command path.
+//	The opts tag of a subcommand only supports required, the help tag is shown in the help menu of the parent.
 //
 // Example:
 //
@@ -328,22 +1074,158 @@ func HelpMenu[T any](s T, details bool) {
 //	}
 //
 // Usage: exec -a=-1.1 -bc 10 --dd-ddd=2 /e "some message"
-func Parse[T any](s T, args []string) T {
-	args = expandArgs(&s, args)
-	args = parseArgs(&s, args)
+//
+// Subcommand example:
+//
+//	args struct {
+//		Help   bool `switch:"h,-help" opts:"help"`
+//		Device *struct {
+//			IP string `switch:"ip" opts:"required"`
+//			On struct {
+//				Help bool `switch:"h,-help" opts:"help" help:"Turn device on."`
+//			} `cmd:"on" help:"Turn device on."`
+//			Info struct{} `cmd:"info" help:"Show device info."`
+//		} `cmd:"device" help:"Control a device."`
+//	}
+//
+// Usage: exec device -ip=127.0.0.1 on
+func ParseE[T any](s T, args []string) (T, error) {
+	s, _, err := ParseResult(s, args)
+	return s, err
+}
 
-	if len(args) > 0 {
-		unknown := []string{}
-		for _, arg := range args {
-			if strings.Contains(arg, "=") {
-				unknown = append(unknown, strings.Split(arg, "=")[0])
-				continue
+// Same as `argp.ParseE` but also returns the selected subcommand path, for example `[]string{"device", "on"}`.
+//
+// The returned path is the deepest command reached, also when an error is returned.
+func ParseCmd[T any](s T, args []string) (T, []string, error) {
+	s, res, err := ParseResult(s, args)
+	return s, res.Cmd, err
+}
+
+// Same as `argp.ParseE` but also returns a `argp.Result` with the selected subcommand path and used deprecated switches.
+//
+// Deprecated switches are not printed, `argp.Parse` prints them as warning to `os.Stderr`.
+func ParseResult[T any](s T, args []string) (T, Result, error) {
+	return parseResult(s, args, map[string]bool{})
+}
+
+func parseResult[T any](s T, args []string, loaded map[string]bool) (T, Result, error) {
+	res := Result{Cmd: []string{}, Deprecated: []*ParseError{}, loaded: loaded}
+	if _, err := Spec(s); err != nil {
+		return s, res, err
+	}
+	args, err := expandArgFiles(args, "", []string{})
+	if err != nil {
+		return s, res, err
+	}
+	err = parseCmd(reflect.ValueOf(&s).Elem(), args, []string{}, &res)
+	return s, res, err
+}
+
+func loadedKey(cmd []string, name string) string {
+	return strings.Join(append(slices.Clone(cmd), name), ".")
+}
+
+// Records the fields of t present in tree, subcommands are read from nested objects.
+func loadedFields(t reflect.Type, tree map[string]any, cmd []string, fields map[string]bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		} else if name == "" {
+			name = field.Name
+		}
+		value, ok := tree[name]
+		for key, val := range tree {
+			if !ok && strings.EqualFold(key, name) {
+				value, ok = val, true
 			}
-			unknown = append(unknown, arg)
 		}
+		if !ok {
+			continue
+		}
+
+		if sub, isObj := value.(map[string]any); isObj && field.Tag.Get("cmd") != "" {
+			loadedFields(field.Type, sub, append(slices.Clone(cmd), field.Tag.Get("cmd")), fields)
+		} else if value != nil {
+			fields[loadedKey(cmd, field.Name)] = true
+		}
+	}
+}
+
+// Loads defaults for s from a JSON config file, such as written by `cfg.DumpAbs`, the returned `Defaults.Value` is s unchanged if the file does not exist.
+//
+// Loaded values precede the default tag but are overwritten by switches and env tags when parsing with the returned `argp.Defaults`, also when the loaded value is zero such as false or 0.
+// Subcommands are loaded from nested objects, fields of type *struct are reset to nil by parsing unless their subcommand is selected.
+//
+// Usage: defs, err := argp.LoadDefaults(args{}, "config.json"); s, err := defs.ParseE(os.Args[1:])
+func LoadDefaults[T any](s T, file string) (Defaults[T], error) {
+	defs := Defaults[T]{Value: s, loaded: map[string]bool{}}
+	bytes, err := os.ReadFile(file)
+	if os.IsNotExist(err) || len(bytes) == 0 {
+		return defs, nil
+	} else if err != nil {
+		return defs, err
+	}
+	if err := json.Unmarshal(bytes, &defs.Value); err != nil {
+		return defs, err
+	}
+	tree := map[string]any{}
+	if err := json.Unmarshal(bytes, &tree); err == nil {
+		loadedFields(reflect.TypeOf(s), tree, []string{}, defs.loaded)
+	}
+	return defs, nil
+}
+
+// Same as `argp.Parse` with the loaded values of d as defaults.
+func (d Defaults[T]) Parse(args []string) T { return parse(d.Value, args, d.loaded) }
+
+// Same as `argp.ParseE` with the loaded values of d as defaults.
+func (d Defaults[T]) ParseE(args []string) (T, error) {
+	s, _, err := parseResult(d.Value, args, d.loaded)
+	return s, err
+}
+
+// Same as `argp.ParseResult` with the loaded values of d as defaults.
+func (d Defaults[T]) ParseResult(args []string) (T, Result, error) {
+	return parseResult(d.Value, args, d.loaded)
+}
 
-		fmt.Println("Unknown arguments '" + strings.Join(unknown, "', '") + "'")
-		HelpMenu(s, false)
+// Parses args into s. Panics if s is not of type struct or a public field doesn't contain a switch tag or has an unsupported type or malformed tag. Private struct fields are ignored.
+// Runs `argp.HelpMenuCmd` and exits gracefully if user input is invalid, output is written using `argp.DefaultHelpFormatter`.
+//
+// See `argp.ParseE` for the struct format, supported types, available tags and opts.
+func Parse[T any](s T, args []string) T { return parse(s, args, map[string]bool{}) }
+
+func parse[T any](s T, args []string, loaded map[string]bool) T {
+	s, res, err := parseResult(s, args, loaded)
+	for _, pErr := range res.Deprecated {
+		fmt.Fprintln(os.Stderr, "warning: "+pErr.Error())
+	}
+	cmd := res.Cmd
+	if pErr := (*ParseError)(nil); errors.As(err, &pErr) {
+		cmd = pErr.Cmd
+	}
+	if errors.Is(err, Errors.NotAStruct) || errors.Is(err, Errors.MissingSwitch) || errors.Is(err, Errors.UnsupportedType) || errors.Is(err, Errors.InvalidTag) {
+		panic(err)
+	} else if errors.Is(err, Errors.HelpRequested) {
+		HelpMenuCmd(s, cmd, true)
+		os.Exit(0)
+	} else if pErr := (*ParseError)(nil); errors.As(err, &pErr) && errors.Is(err, Errors.CompletionRequested) {
+		if err := GenerateCompletion(s, pErr.Token, os.Stdout); err != nil {
+			fmt.Fprint(DefaultHelpFormatter.writer(), err.Error()+DefaultHelpFormatter.lineEnd())
+		}
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprint(DefaultHelpFormatter.writer(), err.Error()+DefaultHelpFormatter.lineEnd())
+		HelpMenuCmd(s, cmd, false)
 		os.Exit(0)
 	}
 
@@ -352,46 +1234,8 @@ func Parse[T any](s T, args []string) T {
 
 // Short for `argp.Parse(s, os.Args[1:])`
 //
-// Parses args into s. Panics if s is not of type struct or a public field doesn't contain a switch tag. Private struct fields are ignored.
-// Runs args.HelpMenu and exits gracefully if user input is invalid.
-//
-// Struct format:
-//
-//	struct { Field type `tag:"value"` }
-//
-// Supported types:
-//
-//	string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64
+// Parses args into s. Panics if s is not of type struct or a public field doesn't contain a switch tag or has an unsupported type. Private struct fields are ignored.
+// Runs `argp.HelpMenuCmd` and exits gracefully if user input is invalid, output is written using `argp.DefaultHelpFormatter`.
 //
-// Available tags:
-//
-//	switch:  List of switches that can map to this field (Required).
-//	prefix:  Prefix for the switches (Default: "-").
-//	opts:    Optional parameters [posistional,required,help].
-//	default: Optional default value.
-//	help:    Help message.
-//
-// Opts:
-//
-//	posistional: Field will be assigned using the first argument without a switch or prefix.
-//	             Needs to be last in the struct, this is to ensure no value arguments are taken from other switches in cases " " is used to seperate key and value arguments.
-//	             As a special case the type of a posistional may be []string to populate this filed with left over arguments, in this case the tags switch, prefix and default wil be ignored and order in stuct non-important.
-//	required:    Field will be required, shows help menu if missing, if a default is given required is ignored.
-//	help:        Quick opt for help menu implementation, when a switch from this field is present `args.HelpMenu` gets called followed by `os.Exit(0)`.
-//	             Needs to be first in the struct, this is to ensure the help menu is show when a help switch is present in cases a panic is trow by other switches.
-//	             The help tag message provided becomes the decription of the executable.
-//
-// Example:
-//
-//	args struct {
-//		Help   bool     `switch:"h,-help"   opts:"help" help:"Some help for exec."`
-//		FieldF []string `opts:"posistional,required"`
-//		FieldA float64  `switch:"a"         prefix:"/" help:"Some help for e."`
-//		FieldB bool     `switch:"b"`
-//		FieldC int      `switch:"c,-cc"     default:"5"`
-//		FieldD uint     `switch:"d,-dd-ddd" default:"7" opts:"required"`
-//		FieldE string   `switch:"e,-ee"     opts:"posistional"`
-//	}
-//
-// Usage: exec -a=-1.1 -bc 10 --dd-ddd=2 /e "some message"
+// See `argp.ParseE` for the struct format, supported types, available tags and opts.
 func ParseArgs[T any](s T) T { return Parse(s, os.Args[1:]) }
@@ -0,0 +1,95 @@
+package argp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type tokenizeTest struct {
+	Bool  bool   `switch:"b,-bool"`
+	Force bool   `switch:"-force"`
+	X     bool   `switch:"x"`
+	Count int    `switch:"v" opts:"count"`
+	Str   string `switch:"s,-str"`
+	Num   int    `switch:"n"`
+	Out   string `switch:"o"`
+}
+
+func TestTokenizeArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		tokens []string
+		rest   []string
+	}{
+		{"empty", []string{}, []string{}, []string{}},
+		{"terminator", []string{"-b", "--", "-s", "x"}, []string{"-b"}, []string{"-s", "x"}},
+		{"terminator as value", []string{"-s", "--", "-b"}, []string{"-s"}, []string{"-b"}},
+		{"negation", []string{"--no-force"}, []string{"--force=false"}, []string{}},
+		{"negation short", []string{"-no-x"}, []string{"-x=false"}, []string{}},
+		{"attached value", []string{"-ovalue"}, []string{"-o=value"}, []string{}},
+		{"attached value with equals", []string{"-o=value"}, []string{"-o=value"}, []string{}},
+		{"negative number", []string{"-n", "-5"}, []string{"-n=-5"}, []string{}},
+		{"switch as value", []string{"-s", "-x"}, []string{"-s", "-x"}, []string{}},
+		{"cluster", []string{"-bvv"}, []string{"-b", "-v", "-v"}, []string{}},
+		{"cluster with value", []string{"-bsfoo"}, []string{"-b", "-s=foo"}, []string{}},
+		{"attached string", []string{"-sfoo"}, []string{"-s=foo"}, []string{}},
+		{"long with value", []string{"--str", "a b"}, []string{"--str=a b"}, []string{}},
+		{"quoted value", []string{"-s", "\"a b\""}, []string{"-s=\"a b\""}, []string{}},
+		{"prefixed value", []string{"-s", "-zzz"}, []string{"-s=-zzz"}, []string{}},
+		{"prefixed long value", []string{"-s", "--unknown"}, []string{"-s=--unknown"}, []string{}},
+		{"unknown switch", []string{"-zzz"}, []string{"-zzz"}, []string{}},
+		{"positional", []string{"-b", "file"}, []string{"-b", "file"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := tokenizeTest{}
+			tokens, rest, err := tokenizeArgs(reflect.ValueOf(&s).Elem(), test.args)
+			if err != nil {
+				t.Fatalf("tokenizeArgs(%q) returned error: %v", test.args, err)
+			}
+			if !slices.Equal(tokens, test.tokens) {
+				t.Errorf("tokenizeArgs(%q) tokens = %q, want %q", test.args, tokens, test.tokens)
+			}
+			if !slices.Equal(rest, test.rest) {
+				t.Errorf("tokenizeArgs(%q) rest = %q, want %q", test.args, rest, test.rest)
+			}
+		})
+	}
+}
+
+func TestExpandArgFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "args"), []byte("-n \"a b\" # comment\n@@daily\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no files", []string{"-n", "x"}, []string{"-n", "x"}},
+		{"file", []string{"@" + filepath.Join(dir, "args"), "-b"}, []string{"-n", "a b", "@daily", "-b"}},
+		{"escaped", []string{"-n", "@@daily"}, []string{"-n", "@daily"}},
+		{"double escaped", []string{"@@@x"}, []string{"@@x"}},
+		{"lone at", []string{"@"}, []string{"@"}},
+		{"terminator", []string{"--", "@missing", "@@x"}, []string{"--", "@missing", "@@x"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandArgFiles(test.args, "", []string{})
+			if err != nil {
+				t.Fatalf("expandArgFiles(%q) returned error: %v", test.args, err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("expandArgFiles(%q) = %q, want %q", test.args, got, test.want)
+			}
+		})
+	}
+}
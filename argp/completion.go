@@ -0,0 +1,292 @@
+package argp
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type (
+	completionCmd struct {
+		path     string
+		cmds     []string
+		cmdHelps []string
+		switches []completionSwitch
+		files    bool
+	}
+
+	completionSwitch struct {
+		switches []string
+		value    bool
+		choices  []string
+		path     bool
+		help     string
+	}
+)
+
+func collectCompletion(v reflect.Value, path string) ([]completionCmd, error) {
+	cmd := completionCmd{path: path, cmds: []string{}, cmdHelps: []string{}, switches: []completionSwitch{}}
+	err := forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		opts := strings.Split(field.Tag.Get("opts"), ",")
+		if isHidden(field) {
+			return nil
+		}
+		if isRest(field) || slices.Contains(opts, "posistional") {
+			cmd.files = cmd.files || slices.Contains(opts, "path")
+			if isRest(field) {
+				return nil
+			}
+		}
+
+		prefix := field.Tag.Get("prefix")
+		if prefix == "" {
+			prefix = "-"
+		}
+		swt := completionSwitch{
+			switches: []string{},
+			value:    !isFlag(field),
+			choices:  []string{},
+			path:     slices.Contains(opts, "path"),
+			help:     field.Tag.Get("help"),
+		}
+		for s := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+			swt.switches = append(swt.switches, prefix+s)
+		}
+		if field.Tag.Get("choices") != "" {
+			swt.choices = strings.Split(field.Tag.Get("choices"), ",")
+		}
+		cmd.switches = append(cmd.switches, swt)
+		return nil
+	})
+	if err != nil {
+		return []completionCmd{}, err
+	}
+
+	cmds := []completionCmd{}
+	err = forEachCmdField(v, func(field reflect.StructField, value reflect.Value) error {
+		if isHidden(field) {
+			return nil
+		}
+		cmd.cmds = append(cmd.cmds, field.Tag.Get("cmd"))
+		cmd.cmdHelps = append(cmd.cmdHelps, field.Tag.Get("help"))
+		if value.Kind() == reflect.Pointer {
+			value = reflect.New(field.Type.Elem()).Elem()
+		}
+		subCmds, err := collectCompletion(value, path+"/"+field.Tag.Get("cmd"))
+		cmds = append(cmds, subCmds...)
+		return err
+	})
+
+	return append([]completionCmd{cmd}, cmds...), err
+}
+
+func shellQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+
+func completionBash(name string, fn string, cmds []completionCmd) string {
+	paths := []string{}
+	for _, cmd := range cmds[1:] {
+		paths = append(paths, shellQuote(cmd.path))
+	}
+
+	script := "_" + fn + "_completion() {\n"
+	script += "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=\"\" i\n"
+	script += "\tif [[ \"$prev\" == \"=\" ]]; then\n\t\tprev=\"${COMP_WORDS[COMP_CWORD-2]}\"\n"
+	script += "\telif [[ \"$cur\" == *=* ]]; then\n\t\tprev=\"${cur%%=*}\"\n\t\tcur=\"${cur#*=}\"\n\tfi\n"
+	if len(paths) > 0 {
+		script += "\tfor ((i=1; i<COMP_CWORD; i++)); do\n"
+		script += "\t\tcase \"$cmd/${COMP_WORDS[i]}\" in\n"
+		script += "\t\t\t" + strings.Join(paths, "|") + ") cmd=\"$cmd/${COMP_WORDS[i]}\" ;;\n"
+		script += "\t\tesac\n\tdone\n"
+	}
+	script += "\tcase \"$cmd\" in\n"
+	for _, cmd := range cmds {
+		words := slices.Clone(cmd.cmds)
+		script += "\t\t" + shellQuote(cmd.path) + ")\n"
+		script += "\t\t\tcase \"$prev\" in\n"
+		for _, swt := range cmd.switches {
+			words = append(words, swt.switches...)
+			if !swt.value {
+				continue
+			}
+			quoted := []string{}
+			for _, s := range swt.switches {
+				quoted = append(quoted, shellQuote(s))
+			}
+			script += "\t\t\t\t" + strings.Join(quoted, "|") + ")"
+			if swt.path {
+				script += " COMPREPLY=($(compgen -f -- \"$cur\"));"
+			} else if len(swt.choices) > 0 {
+				script += " COMPREPLY=($(compgen -W " + shellQuote(strings.Join(swt.choices, " ")) + " -- \"$cur\"));"
+			}
+			script += " return ;;\n"
+		}
+		script += "\t\t\tesac\n"
+		script += "\t\t\tCOMPREPLY=($(compgen -W " + shellQuote(strings.Join(words, " ")) + " -- \"$cur\"))\n"
+		if cmd.files {
+			script += "\t\t\tCOMPREPLY+=($(compgen -f -- \"$cur\"))\n"
+		}
+		script += "\t\t\t;;\n"
+	}
+	script += "\tesac\n}\n\n"
+	script += "complete -F _" + fn + "_completion " + shellQuote(name) + "\n"
+	return script
+}
+
+func completionZsh(name string, fn string, cmds []completionCmd) string {
+	paths := []string{}
+	for _, cmd := range cmds[1:] {
+		paths = append(paths, shellQuote(cmd.path))
+	}
+
+	script := "#compdef " + name + "\n\n"
+	script += "_" + fn + "() {\n"
+	script += "\tlocal cur=\"${words[CURRENT]}\" prev=\"${words[CURRENT-1]}\" cmd=\"\" i\n"
+	script += "\tif [[ \"$cur\" == *=* ]]; then\n\t\tprev=\"${cur%%=*}\"\n\t\tcompset -P '*='\n\tfi\n"
+	if len(paths) > 0 {
+		script += "\tfor ((i=2; i<CURRENT; i++)); do\n"
+		script += "\t\tcase \"$cmd/${words[i]}\" in\n"
+		script += "\t\t\t" + strings.Join(paths, "|") + ") cmd=\"$cmd/${words[i]}\" ;;\n"
+		script += "\t\tesac\n\tdone\n"
+	}
+	script += "\tcase \"$cmd\" in\n"
+	for _, cmd := range cmds {
+		script += "\t\t" + shellQuote(cmd.path) + ")\n"
+		script += "\t\t\tcase \"$prev\" in\n"
+		descrs := []string{}
+		for i, c := range cmd.cmds {
+			descrs = append(descrs, shellQuote(strings.ReplaceAll(c, ":", "\\:")+":"+cmd.cmdHelps[i]))
+		}
+		for _, swt := range cmd.switches {
+			for _, s := range swt.switches {
+				descrs = append(descrs, shellQuote(strings.ReplaceAll(s, ":", "\\:")+":"+swt.help))
+			}
+			if !swt.value {
+				continue
+			}
+			quoted := []string{}
+			for _, s := range swt.switches {
+				quoted = append(quoted, shellQuote(s))
+			}
+			script += "\t\t\t\t" + strings.Join(quoted, "|") + ")"
+			if swt.path {
+				script += " _files;"
+			} else if len(swt.choices) > 0 {
+				choices := []string{}
+				for _, c := range swt.choices {
+					choices = append(choices, shellQuote(c))
+				}
+				script += " compadd -- " + strings.Join(choices, " ") + ";"
+			}
+			script += " return ;;\n"
+		}
+		script += "\t\t\tesac\n"
+		script += "\t\t\tlocal -a opts=(" + strings.Join(descrs, " ") + ")\n"
+		script += "\t\t\t_describe 'argument' opts\n"
+		if cmd.files {
+			script += "\t\t\t_files\n"
+		}
+		script += "\t\t\t;;\n"
+	}
+	script += "\tesac\n}\n\n"
+	script += "compdef _" + fn + " " + shellQuote(name) + "\n"
+	return script
+}
+
+func completionFish(name string, fn string, cmds []completionCmd) string {
+	paths := []string{}
+	for _, cmd := range cmds[1:] {
+		paths = append(paths, shellQuote(cmd.path))
+	}
+
+	script := "function __" + fn + "_argp_cmd\n"
+	script += "\tset -l cmd \"\"\n"
+	if len(paths) > 0 {
+		script += "\tfor w in (commandline -opc)[2..-1]\n"
+		script += "\t\tswitch \"$cmd/$w\"\n"
+		script += "\t\t\tcase " + strings.Join(paths, " ") + "\n"
+		script += "\t\t\t\tset cmd \"$cmd/$w\"\n"
+		script += "\t\tend\n\tend\n"
+	}
+	script += "\ttest \"$cmd\" = \"$argv[1]\"\nend\n\n"
+	script += "complete -c " + shellQuote(name) + " -f\n"
+
+	for _, cmd := range cmds {
+		base := "complete -c " + shellQuote(name) + " -n " + shellQuote("__"+fn+"_argp_cmd "+shellQuote(cmd.path))
+		for i, c := range cmd.cmds {
+			script += base + " -a " + shellQuote(c)
+			if cmd.cmdHelps[i] != "" {
+				script += " -d " + shellQuote(cmd.cmdHelps[i])
+			}
+			script += "\n"
+		}
+		if cmd.files {
+			script += base + " -F\n"
+		}
+		for _, swt := range cmd.switches {
+			line := base
+			for _, s := range swt.switches {
+				switch {
+				case strings.HasPrefix(s, "--") && len(s) > 2:
+					line += " -l " + shellQuote(s[2:])
+				case strings.HasPrefix(s, "-") && len(s) == 2:
+					line += " -s " + shellQuote(s[1:])
+				case strings.HasPrefix(s, "-") && len(s) > 2:
+					line += " -o " + shellQuote(s[1:])
+				default:
+					script += base + " -a " + shellQuote(s)
+					if swt.help != "" {
+						script += " -d " + shellQuote(swt.help)
+					}
+					script += "\n"
+				}
+			}
+			if line == base {
+				continue
+			}
+			if swt.value {
+				line += " -r"
+			}
+			if swt.path {
+				line += " -F"
+			} else if len(swt.choices) > 0 {
+				line += " -a " + shellQuote(strings.Join(swt.choices, " "))
+			}
+			if swt.help != "" {
+				line += " -d " + shellQuote(swt.help)
+			}
+			script += line + "\n"
+		}
+	}
+	return script
+}
+
+// Writes a shell completion script for `s` to `w`, supported shells are bash, zsh and fish.
+//
+// The script completes switches, subcommands and the choices tag, fields with opts path complete file paths.
+// Hidden fields and fields with opts completion are left out, see `argp.ParseE` for the completion opt.
+func GenerateCompletion[T any](s T, shell string, w io.Writer) error {
+	cmds, err := collectCompletion(reflect.ValueOf(&s).Elem(), "")
+	if err != nil {
+		return err
+	}
+
+	name := execName()
+	fn := regexp.MustCompile(`[^a-zA-Z0-9_]`).ReplaceAllString(name, "_")
+	script := ""
+	switch shell {
+	case "bash":
+		script = completionBash(name, fn, cmds)
+	case "zsh":
+		script = completionZsh(name, fn, cmds)
+	case "fish":
+		script = completionFish(name, fn, cmds)
+	default:
+		return &ParseError{Err: Errors.UnsupportedShell, Token: shell}
+	}
+
+	_, err = fmt.Fprint(w, script)
+	return err
+}
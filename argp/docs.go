@@ -0,0 +1,205 @@
+package argp
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+func docRules(f FieldSpec) []string {
+	rules := []string{}
+	for _, rule := range [][2]string{{"choices", strings.Join(f.Choices, ",")}, {"min", f.Min}, {"max", f.Max}, {"regex", f.Regex}, {"oneof", f.OneOf}, {"excludes", strings.Join(f.Excludes, ",")}} {
+		if rule[1] != "" {
+			rules = append(rules, rule[0]+"="+rule[1])
+		}
+	}
+	return rules
+}
+
+func visibleSpec(doc CmdSpec) CmdSpec {
+	doc.Fields = slices.DeleteFunc(slices.Clone(doc.Fields), func(f FieldSpec) bool { return f.Hidden })
+	cmds := []CmdSpec{}
+	for _, cmd := range doc.Cmds {
+		if !cmd.Hidden {
+			cmds = append(cmds, visibleSpec(cmd))
+		}
+	}
+	doc.Cmds = cmds
+	return doc
+}
+
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "-", "\\-")
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = "\\&" + s
+	}
+	return s
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " ")
+}
+
+func writeMan(doc CmdSpec, name string) string {
+	roff := ""
+	if len(doc.Path) > 0 {
+		roff += ".SS \"" + roffEscape(strings.Join(append([]string{name}, doc.Path...), " ")) + "\"\n"
+		if doc.Help != "" {
+			roff += roffEscape(doc.Help) + "\n.PP\n"
+		}
+		roff += ".B " + roffEscape(strings.Join(append([]string{name}, doc.Path...), " ")) + "\n" + roffEscape(strings.TrimSpace(doc.Usage)) + "\n"
+	}
+
+	for _, f := range doc.Fields {
+		roff += ".TP\n"
+		if len(f.Switches) == 0 {
+			roff += ".I " + roffEscape(f.Name) + "\n"
+		} else {
+			swts := []string{}
+			for _, swt := range f.Switches {
+				swts = append(swts, "\\fB"+roffEscape(swt)+"\\fR")
+			}
+			roff += strings.Join(swts, ", ") + " \\fI<" + roffEscape(f.Type.String()) + ">\\fR\n"
+		}
+		if f.Help != "" {
+			roff += roffEscape(f.Help) + "\n.br\n"
+		}
+		details := []string{"Field: " + f.Name}
+		if f.Default != "" {
+			details = append(details, "Default: "+f.Default)
+		}
+		if f.Env != "" {
+			details = append(details, "Env: $"+f.Env)
+		}
+		if len(f.Opts) > 0 {
+			details = append(details, "Opts: "+strings.Join(f.Opts, ","))
+		}
+		details = append(details, docRules(f)...)
+		roff += roffEscape(strings.Join(details, "; ")) + "\n"
+	}
+
+	for _, cmd := range doc.Cmds {
+		roff += writeMan(cmd, name)
+	}
+	return roff
+}
+
+func writeMarkdown(doc CmdSpec, name string) string {
+	md := ""
+	if len(doc.Path) > 0 {
+		md += "### " + strings.Join(append([]string{name}, doc.Path...), " ") + "\n\n"
+		if doc.Help != "" {
+			md += doc.Help + "\n\n"
+		}
+		md += "```\n" + strings.Join(append([]string{name}, doc.Path...), " ") + doc.Usage + "\n```\n\n"
+	}
+
+	if len(doc.Fields) > 0 {
+		md += "| Field | Switches | Type | Default | Env | Opts | Constraints | Help |\n"
+		md += "| --- | --- | --- | --- | --- | --- | --- | --- |\n"
+		for _, f := range doc.Fields {
+			swts := []string{}
+			for _, swt := range f.Switches {
+				swts = append(swts, "`"+swt+"`")
+			}
+			env := ""
+			if f.Env != "" {
+				env = "`$" + f.Env + "`"
+			}
+			def := ""
+			if f.Default != "" {
+				def = "`" + f.Default + "`"
+			}
+			md += "| " + strings.Join([]string{
+				markdownEscape(f.Name),
+				markdownEscape(strings.Join(swts, ", ")),
+				"`" + markdownEscape(f.Type.String()) + "`",
+				markdownEscape(def),
+				markdownEscape(env),
+				markdownEscape(strings.Join(f.Opts, ", ")),
+				markdownEscape(strings.Join(docRules(f), ", ")),
+				markdownEscape(f.Help),
+			}, " | ") + " |\n"
+		}
+		md += "\n"
+	}
+
+	for _, cmd := range doc.Cmds {
+		md += writeMarkdown(cmd, name)
+	}
+	return md
+}
+
+// Writes a roff man page for `s` to `w`, the page is placed in section 1 and named after the executable.
+//
+// The page lists every switch with its aliases, type, default, env, opts, constraints and help, followed by all subcommands.
+// The help tag of the field with opts help becomes the description, hidden fields and fields with opts completion are left out.
+func GenerateMan[T any](s T, w io.Writer) error {
+	doc, err := Spec(s)
+	if err != nil {
+		return err
+	}
+	doc = visibleSpec(doc)
+
+	name := execName()
+	roff := ".TH " + roffEscape(strings.ToUpper(name)) + " 1\n"
+	roff += ".SH NAME\n" + roffEscape(name)
+	if doc.Help != "" {
+		roff += " \\- " + roffEscape(doc.Help)
+	}
+	roff += "\n.SH SYNOPSIS\n.B " + roffEscape(name) + "\n" + roffEscape(strings.TrimSpace(doc.Usage)) + "\n"
+	if doc.Help != "" {
+		roff += ".SH DESCRIPTION\n" + roffEscape(doc.Help) + "\n"
+	}
+	if len(doc.Fields) > 0 {
+		roff += ".SH OPTIONS\n"
+	}
+	cmds := doc.Cmds
+	doc.Cmds = []CmdSpec{}
+	roff += writeMan(doc, name)
+	if len(cmds) > 0 {
+		roff += ".SH COMMANDS\n"
+		for _, cmd := range cmds {
+			roff += writeMan(cmd, name)
+		}
+	}
+
+	_, err = fmt.Fprint(w, roff)
+	return err
+}
+
+// Writes a Markdown reference for `s` to `w`, titled after the executable.
+//
+// The reference lists every switch with its aliases, type, default, env, opts, constraints and help, followed by all subcommands.
+// The help tag of the field with opts help becomes the description, hidden fields and fields with opts completion are left out.
+func GenerateMarkdown[T any](s T, w io.Writer) error {
+	doc, err := Spec(s)
+	if err != nil {
+		return err
+	}
+	doc = visibleSpec(doc)
+
+	name := execName()
+	md := "# " + name + "\n\n"
+	if doc.Help != "" {
+		md += doc.Help + "\n\n"
+	}
+	md += "## Usage\n\n```\n" + name + doc.Usage + "\n```\n\n"
+	cmds := doc.Cmds
+	doc.Cmds = []CmdSpec{}
+	if len(doc.Fields) > 0 {
+		md += "## Options\n\n"
+	}
+	md += writeMarkdown(doc, name)
+	if len(cmds) > 0 {
+		md += "## Commands\n\n"
+		for _, cmd := range cmds {
+			md += writeMarkdown(cmd, name)
+		}
+	}
+
+	_, err = fmt.Fprint(w, strings.TrimSuffix(md, "\n"))
+	return err
+}
@@ -1,3 +1,7 @@
 module github.com/HandyGold75/GOLib/argp
 
 go 1.25.6
+
+require golang.org/x/term v0.39.0
+
+require golang.org/x/sys v0.40.0 // indirect
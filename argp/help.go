@@ -0,0 +1,310 @@
+package argp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+type (
+	// Formatter for help menus, the zero value writes to `os.Stdout` without wrapping or colors.
+	HelpFormatter struct {
+		// Writer the help menu is written to (Default: `os.Stdout`).
+		Writer io.Writer
+		// Program name shown in the help menu (Default: name of `os.Executable`).
+		Name string
+		// Width to wrap help messages at, when 0 the terminal width of Writer is used, when negative or Writer is not a terminal help messages are not wrapped.
+		Width int
+		// Usage examples shown at the end of the detailed help menu, for example "exec -ip=127.0.0.1 on".
+		Examples []string
+		// Use colors for headings, field names and switches.
+		Color bool
+		// Line ending (Default: "\n").
+		LineEnd string
+	}
+)
+
+const (
+	helpBold  = "\033[1m"
+	helpCyan  = "\033[36m"
+	helpReset = "\033[0m"
+)
+
+var (
+	// Formatter used by `argp.HelpMenu`, `argp.HelpMenuCmd` and `argp.Parse`.
+	//
+	// The Name is also used by `argp.GenerateCompletion`, `argp.GenerateMan` and `argp.GenerateMarkdown`.
+	DefaultHelpFormatter = HelpFormatter{Writer: os.Stdout, LineEnd: "\r\n"}
+)
+
+func execName() string {
+	if DefaultHelpFormatter.Name != "" {
+		return DefaultHelpFormatter.Name
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return "exec"
+	}
+	execPathSplit := strings.Split(strings.ReplaceAll(execPath, "\\", "/"), "/")
+	return execPathSplit[len(execPathSplit)-1]
+}
+
+func usageArgs(v reflect.Value) string {
+	argsStr := ""
+	_ = forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isHidden(field) {
+			return nil
+		}
+		prefix := field.Tag.Get("prefix")
+		if prefix == "" {
+			prefix = "-"
+		}
+
+		switch {
+		case isRest(field):
+			argsStr = " [" + field.Name + "...]" + argsStr
+
+		case isCount(field):
+			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + "]..."
+
+		case field.Type.String() == "bool":
+			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + "]"
+
+		case isMulti(field.Type):
+			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]..."
+
+		default:
+			argsStr += " [" + prefix + strings.Split(field.Tag.Get("switch"), ",")[0] + " <" + field.Type.String() + ">]"
+		}
+		return nil
+	})
+
+	cmds := []string{}
+	_ = forEachCmdField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isHidden(field) {
+			return nil
+		}
+		cmds = append(cmds, field.Tag.Get("cmd"))
+		return nil
+	})
+	if len(cmds) > 0 {
+		argsStr += " {" + strings.Join(cmds, "|") + "}"
+	}
+	return argsStr
+}
+
+func wrapText(text string, width int, indent string) []string {
+	indentWidth := len(strings.ReplaceAll(indent, "\t", "        "))
+	if width <= 0 || width-indentWidth < 16 {
+		return []string{indent + text}
+	}
+
+	lines := []string{}
+	line := ""
+	for word := range strings.FieldsSeq(text) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width-indentWidth {
+			lines = append(lines, indent+line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, indent+line)
+}
+
+func (hf HelpFormatter) width() int {
+	if hf.Width != 0 {
+		return hf.Width
+	}
+	f, ok := hf.writer().(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+func (hf HelpFormatter) writer() io.Writer {
+	if hf.Writer == nil {
+		return os.Stdout
+	}
+	return hf.Writer
+}
+
+func (hf HelpFormatter) lineEnd() string {
+	if hf.LineEnd == "" {
+		return "\n"
+	}
+	return hf.LineEnd
+}
+
+func (hf HelpFormatter) color(col string, s string) string {
+	if !hf.Color {
+		return s
+	}
+	return col + s + helpReset
+}
+
+func (hf HelpFormatter) helpMenu(v reflect.Value, cmd []string, details bool) error {
+	v, err := cmdValue(v, cmd)
+	if err != nil {
+		return err
+	}
+
+	nl := hf.lineEnd()
+	width := hf.width()
+	name := hf.Name
+	if name == "" {
+		name = execName()
+	}
+
+	maxLenSwts := 0
+	groups := []string{""}
+	err = forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if !slices.Contains(groups, field.Tag.Get("group")) {
+			groups = append(groups, field.Tag.Get("group"))
+		}
+		if isRest(field) || isHidden(field) {
+			return nil
+		}
+		lenSwt := len(field.Tag.Get("switch")) + max(1, len(field.Tag.Get("prefix"))) + (len(strings.Split(field.Tag.Get("switch"), ",")))
+		if lenSwt > maxLenSwts {
+			maxLenSwts = lenSwt
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxLenCmds := 0
+	err = forEachCmdField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isHidden(field) {
+			return nil
+		}
+		maxLenCmds = max(maxLenCmds, len(field.Tag.Get("cmd")))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	mainHelpMsg := ""
+	helpMenuStr := ""
+	for _, group := range groups {
+		groupStr := ""
+		_ = forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+			if field.Tag.Get("group") != group || isHidden(field) {
+				return nil
+			}
+			prefix := field.Tag.Get("prefix")
+			if prefix == "" {
+				prefix = "-"
+			}
+
+			swts := ""
+			if !isRest(field) {
+				for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+					swts += " " + prefix + swt
+				}
+			}
+
+			groupStr += hf.color(helpBold, field.Name) + nl
+			groupStr += hf.color(helpCyan, fmt.Sprintf(" %-"+strconv.Itoa(maxLenSwts)+"v", swts)) + fmt.Sprintf("  %-9v", "<"+field.Type.String()+">")
+			if field.Tag.Get("opts") != "" {
+				groupStr += fmt.Sprintf(" (%v)", field.Tag.Get("opts"))
+			}
+			if field.Tag.Get("env") != "" {
+				groupStr += fmt.Sprintf(" [$%v]", field.Tag.Get("env"))
+			}
+			for _, tag := range []string{"choices", "min", "max", "regex", "oneof", "excludes"} {
+				if field.Tag.Get(tag) != "" {
+					groupStr += fmt.Sprintf(" %v=%v", tag, field.Tag.Get(tag))
+				}
+			}
+			groupStr += nl
+			if field.Tag.Get("help") != "" {
+				if slices.Contains(strings.Split(field.Tag.Get("opts"), ","), "help") {
+					mainHelpMsg = strings.Join(wrapText(field.Tag.Get("help"), width, "\t"), nl) + nl
+					return nil
+				}
+				groupStr += strings.Join(wrapText(field.Tag.Get("help"), width, "\t"), nl) + nl
+			}
+			return nil
+		})
+		if group != "" && groupStr != "" {
+			groupStr = nl + hf.color(helpBold, group+":") + nl + groupStr
+		}
+		helpMenuStr += groupStr
+	}
+
+	cmdMenuStr := ""
+	_ = forEachCmdField(v, func(field reflect.StructField, _ reflect.Value) error {
+		if isHidden(field) {
+			return nil
+		}
+		cmdMenuStr += hf.color(helpCyan, fmt.Sprintf(" %-"+strconv.Itoa(maxLenCmds)+"v", field.Tag.Get("cmd")))
+		if field.Tag.Get("opts") != "" {
+			cmdMenuStr += fmt.Sprintf("  (%v)", field.Tag.Get("opts"))
+		}
+		if field.Tag.Get("help") != "" {
+			cmdMenuStr += "  " + field.Tag.Get("help")
+		}
+		cmdMenuStr += nl
+		return nil
+	})
+	if cmdMenuStr != "" {
+		cmdMenuStr = hf.color(helpBold, "Commands:") + nl + cmdMenuStr + nl
+	}
+
+	examplesStr := ""
+	for _, example := range hf.Examples {
+		examplesStr += " " + example + nl
+	}
+	if examplesStr != "" {
+		examplesStr = nl + hf.color(helpBold, "Examples:") + nl + examplesStr
+	}
+
+	helpStr := hf.color(helpBold, "Usage:") + " " + strings.Join(append([]string{name}, cmd...), " ") + usageArgs(v) + nl
+	if details {
+		helpStr += mainHelpMsg + nl + cmdMenuStr + helpMenuStr + examplesStr
+	}
+	_, err = fmt.Fprint(hf.writer(), helpStr)
+	return err
+}
+
+// Writes the help menu related to the subcommand `cmd` of `s`, an empty `cmd` writes the help menu of `s` itself.
+// Returns an error if `s` is not of type struct, a public field doesn't contain a switch tag or `cmd` is not a subcommand of `s`. Private struct fields are ignored.
+//
+// Fields with a group tag are listed under a heading with the group name, after the fields without a group.
+func (hf HelpFormatter) HelpMenu(s any, cmd []string, details bool) error {
+	v := reflect.ValueOf(s)
+	if !v.IsValid() {
+		return &ParseError{Err: Errors.NotAStruct}
+	}
+	sCopy := reflect.New(v.Type()).Elem()
+	sCopy.Set(v)
+	return hf.helpMenu(sCopy, cmd, details)
+}
+
+// Shows help menu related to `s` using `argp.DefaultHelpFormatter`. Panics if `s` is not of type struct or a public field doesn't contain a switch tag. Private struct fields are ignored.
+func HelpMenu[T any](s T, details bool) { HelpMenuCmd(s, []string{}, details) }
+
+// Shows help menu related to the subcommand `cmd` of `s` using `argp.DefaultHelpFormatter`, an empty `cmd` shows the help menu of `s` itself.
+// Panics if `s` is not of type struct, a public field doesn't contain a switch tag or `cmd` is not a subcommand of `s`. Private struct fields are ignored.
+func HelpMenuCmd[T any](s T, cmd []string, details bool) {
+	if err := DefaultHelpFormatter.helpMenu(reflect.ValueOf(&s).Elem(), cmd, details); err != nil {
+		panic(err)
+	}
+}
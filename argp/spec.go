@@ -0,0 +1,257 @@
+package argp
+
+import (
+	"encoding"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Description of a struct or subcommand, returned by `argp.Spec`.
+	CmdSpec struct {
+		// Name of the subcommand, empty for the top level.
+		Name string
+		// Subcommand path, for example `[]string{"device", "on"}`, empty for the top level.
+		Path []string
+		// Usage arguments as shown in the help menu, for example " [-a <string>] {on|off}".
+		Usage string
+		// Help tag of the subcommand, replaced by the help tag of a field with opts help.
+		Help string
+		// Opts tag of the subcommand.
+		Opts []string
+		// Subcommand has hidden tag "true".
+		Hidden bool
+		// Fields in struct order, including hidden fields.
+		Fields []FieldSpec
+		// Subcommands in struct order, including hidden subcommands.
+		Cmds []CmdSpec
+	}
+
+	// Description of a field, see `argp.ParseE` for the meaning of the tags.
+	FieldSpec struct {
+		// Name of the struct field.
+		Name string
+		// Switches including prefix, for example `[]string{"-a", "--addr"}`, empty for the rest posistional.
+		Switches []string
+		// Prefix of the switches (Default: "-").
+		Prefix string
+		// Type of the struct field.
+		Type reflect.Type
+		// Field is repeatable, see Repeatable switches of `argp.ParseE`.
+		Multi bool
+		// Field is the []string without switch tag populated with left over arguments.
+		Rest bool
+
+		Default    string
+		Env        string
+		Layout     string
+		Opts       []string
+		Choices    []string
+		Min        string
+		Max        string
+		Regex      string
+		OneOf      string
+		Excludes   []string
+		Help       string
+		Group      string
+		Deprecated string
+		Hidden     bool
+	}
+)
+
+var (
+	fieldOpts = []string{"posistional", "required", "completion", "path", "help", "count", "prompt", "secret"}
+	cmdOpts   = []string{"required"}
+)
+
+func splitTag(tag string) []string {
+	if tag == "" {
+		return []string{}
+	}
+	return strings.Split(tag, ",")
+}
+
+func supportedType(t reflect.Type) bool {
+	if isMulti(t) {
+		if t.Kind() == reflect.Map && !supportedType(t.Key()) {
+			return false
+		}
+		return supportedType(t.Elem())
+	}
+	switch t.String() {
+	case "time.Duration", "time.Time", "url.URL":
+		return true
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func checkLimit(t reflect.Type, limit string, layout string) bool {
+	if isMulti(t) {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		_, err := strconv.Atoi(limit)
+		return err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return setValue(reflect.New(t).Elem(), limit, layout) == nil
+	}
+	return false
+}
+
+func fieldSpec(field reflect.StructField) (FieldSpec, error) {
+	prefix := field.Tag.Get("prefix")
+	if prefix == "" {
+		prefix = "-"
+	}
+	f := FieldSpec{
+		Name:       field.Name,
+		Switches:   []string{},
+		Prefix:     prefix,
+		Type:       field.Type,
+		Multi:      isMulti(field.Type),
+		Rest:       isRest(field),
+		Default:    field.Tag.Get("default"),
+		Env:        field.Tag.Get("env"),
+		Layout:     field.Tag.Get("layout"),
+		Opts:       splitTag(field.Tag.Get("opts")),
+		Choices:    splitTag(field.Tag.Get("choices")),
+		Min:        field.Tag.Get("min"),
+		Max:        field.Tag.Get("max"),
+		Regex:      field.Tag.Get("regex"),
+		OneOf:      field.Tag.Get("oneof"),
+		Excludes:   splitTag(field.Tag.Get("excludes")),
+		Help:       field.Tag.Get("help"),
+		Group:      field.Tag.Get("group"),
+		Deprecated: field.Tag.Get("deprecated"),
+		Hidden:     isHidden(field),
+	}
+	if !f.Rest {
+		for swt := range strings.SplitSeq(field.Tag.Get("switch"), ",") {
+			if swt == "" {
+				return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: field.Tag.Get("switch")}
+			}
+			f.Switches = append(f.Switches, prefix+swt)
+		}
+	}
+
+	if !supportedType(field.Type) {
+		return f, &ParseError{Err: Errors.UnsupportedType, Field: field.Name, Token: field.Type.String()}
+	}
+	for _, opt := range f.Opts {
+		if !slices.Contains(fieldOpts, opt) {
+			return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: opt}
+		}
+	}
+	if hidden := field.Tag.Get("hidden"); hidden != "" && hidden != "true" && hidden != "false" {
+		return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: hidden}
+	}
+	if isCount(field) && !slices.Contains([]reflect.Kind{reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64}, field.Type.Kind()) {
+		return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: "count"}
+	}
+	if f.Regex != "" {
+		if _, err := regexp.Compile(f.Regex); err != nil {
+			return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: f.Regex}
+		}
+	}
+	for _, limit := range []string{f.Min, f.Max} {
+		if limit != "" && !checkLimit(field.Type, limit, f.Layout) {
+			return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: limit}
+		}
+	}
+
+	elem := field.Type
+	if f.Multi {
+		elem = elem.Elem()
+	}
+	for _, choice := range f.Choices {
+		if err := setValue(reflect.New(elem).Elem(), choice, f.Layout); err != nil {
+			return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: choice}
+		}
+	}
+	if f.Default != "" && !f.Rest {
+		vals := []string{f.Default}
+		if f.Multi {
+			vals = strings.Split(f.Default, ",")
+		}
+		for _, val := range vals {
+			if err := setValue(reflect.New(field.Type).Elem(), val, f.Layout); err != nil {
+				return f, &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: val}
+			}
+		}
+	}
+	return f, nil
+}
+
+func cmdSpec(v reflect.Value, path []string, help string) (CmdSpec, error) {
+	spec := CmdSpec{Path: path, Usage: usageArgs(v), Help: help, Opts: []string{}, Fields: []FieldSpec{}, Cmds: []CmdSpec{}}
+	if len(path) > 0 {
+		spec.Name = path[len(path)-1]
+	}
+
+	err := forEachStructField(v, func(field reflect.StructField, _ reflect.Value) error {
+		f, err := fieldSpec(field)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(f.Opts, "help") && f.Help != "" {
+			spec.Help = f.Help
+		}
+		spec.Fields = append(spec.Fields, f)
+		return nil
+	})
+	if err != nil {
+		return spec, setErrCmd(err, path)
+	}
+	for _, f := range spec.Fields {
+		for _, name := range f.Excludes {
+			if !slices.ContainsFunc(spec.Fields, func(f FieldSpec) bool { return f.Name == name }) {
+				return spec, setErrCmd(&ParseError{Err: Errors.InvalidTag, Field: f.Name, Token: name}, path)
+			}
+		}
+	}
+
+	err = forEachCmdField(v, func(field reflect.StructField, value reflect.Value) error {
+		if value.Kind() == reflect.Pointer {
+			value = reflect.New(field.Type.Elem()).Elem()
+		}
+		sub, err := cmdSpec(value, append(slices.Clone(path), field.Tag.Get("cmd")), field.Tag.Get("help"))
+		if err != nil {
+			return err
+		}
+		sub.Opts = splitTag(field.Tag.Get("opts"))
+		sub.Hidden = isHidden(field)
+		for _, opt := range sub.Opts {
+			if !slices.Contains(cmdOpts, opt) {
+				return &ParseError{Err: Errors.InvalidTag, Field: field.Name, Token: opt}
+			}
+		}
+		spec.Cmds = append(spec.Cmds, sub)
+		return nil
+	})
+	return spec, setErrCmd(err, path)
+}
+
+// Returns a description of every field and subcommand of `s`, for generating documentation, forms or menus from the same definition.
+// Returns an error if `s` is not of type struct, a public field doesn't contain a switch tag, has an unsupported type or a malformed tag. Private struct fields are ignored.
+//
+// Tags are validated up front, opts, regex, min, max, excludes, choices and default must be valid for the field type.
+// `argp.ParseE` runs the same validation before parsing.
+func Spec[T any](s T) (CmdSpec, error) {
+	return cmdSpec(reflect.ValueOf(&s).Elem(), []string{}, "")
+}
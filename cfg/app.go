@@ -0,0 +1,111 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+type (
+	// Dirs of an application returned by `cfg.App`, each dir is namespaced by the application name.
+	AppDirs struct {
+		// Name of the application.
+		Name string
+		// Config files, `$XDG_CONFIG_HOME/<name>` or `~/.config/<name>`.
+		ConfigDir string
+		// Non essential cached data, `$XDG_CACHE_HOME/<name>` or `~/.cache/<name>`.
+		CacheDir string
+		// User data, `$XDG_DATA_HOME/<name>` or `~/.local/share/<name>`.
+		DataDir string
+		// State that persists between runs such as logs and history, `$XDG_STATE_HOME/<name>` or `~/.local/state/<name>`.
+		StateDir string
+	}
+)
+
+// Returns the value of env if it is an absolute path, relative paths are ignored as required by the XDG spec.
+func xdgEnv(env string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return ""
+}
+
+// Returns the base dir for env, fallback is used when env is not set and the os has no native dir.
+func xdgDir(env string, home string, fallback string) string {
+	if dir := xdgEnv(env); dir != "" {
+		return dir
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if env == "XDG_CONFIG_HOME" {
+			if dir := os.Getenv("AppData"); dir != "" {
+				return dir
+			}
+		} else if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir
+		}
+	case "darwin", "ios":
+		if env == "XDG_CACHE_HOME" {
+			return filepath.Join(home, "Library/Caches")
+		}
+		return filepath.Join(home, "Library/Application Support")
+	case "plan9":
+		if env == "XDG_CACHE_HOME" {
+			return filepath.Join(home, "lib/cache")
+		}
+		return filepath.Join(home, "lib")
+	}
+	return filepath.Join(home, fallback)
+}
+
+// Resolves the dirs of application name following the XDG Base Directory spec.
+//
+// Absolute paths in the env vars `XDG_CONFIG_HOME`, `XDG_CACHE_HOME`, `XDG_DATA_HOME` and `XDG_STATE_HOME` take precedence on every os.
+// Without them the native dirs are used on windows (`%AppData%` for config, `%LocalAppData%` for the others) and macOS (`~/Library/Caches` for cache, `~/Library/Application Support` for the others).
+//
+// Dirs are not created until a file is written in them. Log files belong in `AppDirs.StateDir`, for example `logger.NewAbs(app.StateDir + "/<name>.log")`.
+func App(name string) (AppDirs, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return AppDirs{}, err
+	}
+	return AppDirs{
+		Name:      name,
+		ConfigDir: filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), name),
+		CacheDir:  filepath.Join(xdgDir("XDG_CACHE_HOME", home, ".cache"), name),
+		DataDir:   filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local/share"), name),
+		StateDir:  filepath.Join(xdgDir("XDG_STATE_HOME", home, ".local/state"), name),
+	}, nil
+}
+
+// Returns the path of config file name, `<ConfigDir>/<name>.json`.
+func (app AppDirs) File(name string) string {
+	return filepath.Join(app.ConfigDir, name+".json")
+}
+
+// Loads config file into data.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// Config file is stored in `./<name>.json` relative to `AppDirs.ConfigDir`.
+func (app AppDirs) Load(name string, data any) error { return LoadAbs(app.File(name), data) }
+
+// Dumps data to config file.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// Config file is stored in `./<name>.json` relative to `AppDirs.ConfigDir`.
+func (app AppDirs) Dump(name string, data any) error { return DumpAbs(app.File(name), data) }
+
+// Returns path if file exists, else returns empty string.
+//
+// File is checked in `./<name>.json` relative to `AppDirs.ConfigDir`.
+func (app AppDirs) Check(name string) string { return CheckAbs(app.File(name)) }
+
+// Returns path if dir exists, else returns empty string.
+//
+// Dir is checked in `./<name>` relative to `AppDirs.ConfigDir`.
+func (app AppDirs) CheckDir(name string) string {
+	return CheckDirAbs(filepath.Join(app.ConfigDir, name))
+}
@@ -1,8 +1,8 @@
 package cfg
 
 import (
-	"encoding/json"
 	"os"
+	"reflect"
 	"strings"
 )
 
@@ -36,29 +36,49 @@ func LoadRel(name string, data any) error {
 // Loads config file into data.
 //
 // If config file is not present then it tries creating it with content of data.
-func LoadAbs(file string, data any) error {
+//
+// The codec is chosen by the extension of file, see `cfg.CodecFor`.
+func LoadAbs(file string, data any) error { return LoadCodec(file, data, CodecFor(file)) }
+
+// Loads config file into data using codec.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// Fields missing from the config file are filled from their default tag and data is validated, see `cfg.ApplySchema`.
+// The config file is rewritten when a default was filled, so it contains every field.
+// Config files of an older version are upgraded in place using the migrations registered with `cfg.RegisterMigration`.
+//
+// Fields tagged `secret:"true"` are decrypted, secrets stored as plain text are encrypted in place, see `cfg.DumpCodec`.
+func LoadCodec(file string, data any, codec Codec) error {
 	bytes, err := os.ReadFile(file)
 	if os.IsNotExist(err) || len(bytes) == 0 {
-		fileSplit := strings.Split(strings.ReplaceAll(file, "\\", "/"), "/")
-		if err := os.MkdirAll(strings.Join(fileSplit[:len(fileSplit)-1], "/"), os.ModePerm); err != nil {
+		if _, err := applyDefaults(reflect.ValueOf(data), "", nil); err != nil {
 			return err
 		}
-		bytes, err = json.MarshalIndent(data, "", "\t")
-		if err != nil {
+		if version, ok := versionField(reflect.Indirect(reflect.ValueOf(data))); ok && version.Int() == 0 && version.CanSet() {
+			version.SetInt(int64(max(0, latestVersion(migrationsFor(reflect.TypeOf(data).Elem())))))
+		}
+		if err := validate(reflect.ValueOf(data)); err != nil {
 			return err
 		}
-		if err = os.WriteFile(file, bytes, os.ModePerm); err != nil {
+		bytes, err = marshal(data, codec)
+		if err != nil {
 			return err
 		}
+		return writeFile(file, bytes)
 	} else if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(bytes, data)
+	migrated, err := decode(bytes, data, codec)
+	if err != nil || !migrated {
+		return err
+	}
+	bytes, err = marshal(data, codec)
 	if err != nil {
 		return err
 	}
-	return nil
+	return writeFile(file, bytes)
 }
 
 // Dumps data to config file.
@@ -91,25 +111,28 @@ func DumpRel(name string, data any) error {
 // Dumps data to config file.
 //
 // If config file is not present then it tries creating it with content of data.
-func DumpAbs(file string, data any) error {
-	bytes, err := json.MarshalIndent(data, "", "  ")
+//
+// The codec is chosen by the extension of file, see `cfg.CodecFor`.
+func DumpAbs(file string, data any) error { return DumpCodec(file, data, CodecFor(file)) }
+
+// Dumps data to config file using codec.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// Returns an error without writing if data is invalid, see `cfg.ApplySchema`.
+//
+// String fields tagged `secret:"true"` are stored encrypted as "golib-secret:v1:<base64>", other values stay readable.
+// The passphrase is read from the env var `cfg.SecretEnv`, the file `cfg.SecretKeyFile` or prompted for on the terminal.
+func DumpCodec(file string, data any, codec Codec) error {
+	if err := validate(reflect.ValueOf(data)); err != nil {
+		return err
+	}
+	bytes, err := marshal(data, codec)
 	if err != nil {
 		return err
 	}
 
-	if err = os.WriteFile(file, bytes, os.ModePerm); err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
-		fileSplit := strings.Split(strings.ReplaceAll(file, "\\", "/"), "/")
-		if err := os.MkdirAll(strings.Join(fileSplit[:len(fileSplit)-1], "/"), os.ModePerm); err != nil {
-			return err
-		}
-		if err = os.WriteFile(file, bytes, os.ModePerm); err != nil {
-			return err
-		}
-	}
-	return nil
+	return writeFile(file, bytes)
 }
 
 // Returns path if file exists, else returns empty string.
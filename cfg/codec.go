@@ -0,0 +1,200 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Encoding of a config file, used by `cfg.LoadCodec` and `cfg.DumpCodec`.
+	Codec interface {
+		Marshal(data any) ([]byte, error)
+		Unmarshal(bytes []byte, data any) error
+	}
+
+	jsonCodec struct{ strict bool }
+	yamlCodec struct{}
+	tomlCodec struct{}
+)
+
+var (
+	Errors = struct {
+		NotAStruct, UnsupportedType, InvalidSyntax, InvalidValue, UnknownVersion, MissingMigration, MissingKey, InvalidSecret, UnknownField error
+	}{
+		NotAStruct:       errors.New("data is not a struct"),
+		UnsupportedType:  errors.New("unsupported type"),
+		InvalidSyntax:    errors.New("invalid syntax"),
+		InvalidValue:     errors.New("invalid value for"),
+		UnknownVersion:   errors.New("unknown config version"),
+		MissingMigration: errors.New("no migration registered from version"),
+		MissingKey:       errors.New("no secret key available for"),
+		InvalidSecret:    errors.New("unable to decrypt secret"),
+		UnknownField:     errors.New("unknown field"),
+	}
+
+	// Available codecs, all codecs use the json tags of data for key names.
+	//
+	// JSON reads `// line` and `/* block */` comments and trailing commas, used for .json, .jsonc and .json5 files. Comments are not preserved when the file is written.
+	// StrictJSON reads like JSON but rejects keys without a matching field, reporting the key with its line and column. Unknown keys in files upgraded by a migration are reported without position.
+	Codecs = struct {
+		JSON, StrictJSON, YAML, TOML, INI Codec
+	}{
+		JSON:       jsonCodec{},
+		StrictJSON: jsonCodec{strict: true},
+		YAML:       yamlCodec{},
+		TOML:       tomlCodec{},
+		INI:        iniCodec{},
+	}
+
+	codecsMu = sync.RWMutex{}
+	codecs   = map[string]Codec{
+		".json":  Codecs.JSON,
+		".jsonc": Codecs.JSON,
+		".json5": Codecs.JSON,
+		".yaml":  Codecs.YAML,
+		".yml":   Codecs.YAML,
+		".toml":  Codecs.TOML,
+		".ini":   Codecs.INI,
+	}
+)
+
+// Registers codec for files ending with ext, for example ".hcl". Overwrites the codec previously registered for ext.
+func RegisterCodec(ext string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[strings.ToLower(ext)] = codec
+}
+
+// Returns the codec registered for the extension of file, defaults to `cfg.Codecs.JSON` for unknown extensions.
+func CodecFor(file string) Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	if codec, ok := codecs[strings.ToLower(filepath.Ext(file))]; ok {
+		return codec
+	}
+	return Codecs.JSON
+}
+
+// Converts data to a generic tree of maps, slices and scalars using the json tags of data.
+func toTree(data any) (any, error) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(bytes)))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return convertNumbers(tree), nil
+}
+
+func convertNumbers(tree any) any {
+	switch t := tree.(type) {
+	case map[string]any:
+		for k, v := range t {
+			if v == nil {
+				delete(t, k)
+				continue
+			}
+			t[k] = convertNumbers(v)
+		}
+	case []any:
+		for i, v := range t {
+			t[i] = convertNumbers(v)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	}
+	return tree
+}
+
+// Decodes a generic tree into data using the json tags of data.
+func fromTree(tree any, data any) error {
+	bytes, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, data)
+}
+
+func (jsonCodec) Marshal(data any) ([]byte, error) { return json.MarshalIndent(data, "", "\t") }
+
+func (c jsonCodec) Unmarshal(bytes []byte, data any) error {
+	return unmarshalJSONC(bytes, data, c.strict)
+}
+
+func (yamlCodec) Marshal(data any) ([]byte, error) {
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, decoding it into a node keeps the field order of data.
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(jsonBytes, &node); err != nil {
+		return nil, err
+	}
+	var clearStyle func(*yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			clearStyle(c)
+		}
+	}
+	clearStyle(&node)
+	buf := bytes.Buffer{}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (yamlCodec) Unmarshal(bytes []byte, data any) error {
+	var tree any
+	if err := yaml.Unmarshal(bytes, &tree); err != nil {
+		return err
+	}
+	return fromTree(tree, data)
+}
+
+func (tomlCodec) Marshal(data any) ([]byte, error) {
+	tree, err := toTree(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tree.(map[string]any); !ok {
+		return nil, Errors.NotAStruct
+	}
+	buf := bytes.Buffer{}
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (tomlCodec) Unmarshal(bytes []byte, data any) error {
+	tree := map[string]any{}
+	if err := toml.Unmarshal(bytes, &tree); err != nil {
+		return err
+	}
+	return fromTree(tree, data)
+}
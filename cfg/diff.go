@@ -0,0 +1,154 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+type (
+	// Changed value returned by `cfg.Diff`.
+	Change struct {
+		// Dotted json path of the value, for example "db.host" or "hosts.web".
+		Path string
+		// Values before and after the change, nil when the value was added or removed.
+		Old, New any
+	}
+)
+
+// Replaces the values of fields tagged `secret:"true"` in a `cfg.Change`.
+var SecretMask = "******"
+
+func (c Change) String() string { return fmt.Sprintf("%v: %v -> %v", c.Path, c.Old, c.New) }
+
+func maskSecret(v reflect.Value) any {
+	if !v.IsValid() || v.IsZero() {
+		return ""
+	}
+	return SecretMask
+}
+
+func valueOf(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// Values that marshal themselves such as `time.Time` are compared as a whole, their fields are often unexported.
+func isLeaf(t reflect.Type) bool {
+	return iniIsText(t) || reflect.PointerTo(t).Implements(reflect.TypeFor[json.Marshaler]())
+}
+
+// Follows pointers and interfaces, nil pointers and interfaces become invalid.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// Returns true if old and new are equal, values that marshal themselves are compared by their json so for example equal times in other locations match.
+func equal(old, new reflect.Value) bool {
+	if !old.IsValid() || !new.IsValid() || old.Type() != new.Type() {
+		return !old.IsValid() && !new.IsValid()
+	} else if !isLeaf(old.Type()) {
+		return reflect.DeepEqual(old.Interface(), new.Interface())
+	}
+	oldBytes, oldErr := json.Marshal(old.Interface())
+	newBytes, newErr := json.Marshal(new.Interface())
+	if oldErr != nil || newErr != nil {
+		return reflect.DeepEqual(old.Interface(), new.Interface())
+	}
+	return string(oldBytes) == string(newBytes)
+}
+
+func diff(old, new reflect.Value, path string, secret bool, changes []Change) []Change {
+	old, new = indirect(old), indirect(new)
+	// An added or removed struct is compared against its zero value, so its secret fields are masked.
+	if !old.IsValid() && new.IsValid() && new.Kind() == reflect.Struct {
+		old = reflect.Zero(new.Type())
+	} else if old.IsValid() && !new.IsValid() && old.Kind() == reflect.Struct {
+		new = reflect.Zero(old.Type())
+	}
+	if old.IsValid() && new.IsValid() && old.Type() == new.Type() && !isLeaf(old.Type()) && !secret {
+		switch old.Kind() {
+		case reflect.Struct:
+			for i := range old.NumField() {
+				field := old.Type().Field(i)
+				if key, ok := iniKey(field); ok {
+					changes = diff(old.Field(i), new.Field(i), path+key+".", field.Tag.Get("secret") == "true", changes)
+				}
+			}
+			return changes
+
+		case reflect.Map:
+			keys := map[string]reflect.Value{}
+			for _, m := range []reflect.Value{old, new} {
+				for iter := m.MapRange(); iter.Next(); {
+					keys[fmt.Sprint(iter.Key().Interface())] = iter.Key()
+				}
+			}
+			names := []string{}
+			for name := range keys {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				changes = diff(old.MapIndex(keys[name]), new.MapIndex(keys[name]), path+name+".", false, changes)
+			}
+			return changes
+		}
+	}
+
+	if equal(old, new) {
+		return changes
+	}
+	path = strings.TrimSuffix(path, ".")
+	if secret {
+		return append(changes, Change{Path: path, Old: maskSecret(old), New: maskSecret(new)})
+	}
+	return append(changes, Change{Path: path, Old: valueOf(old), New: valueOf(new)})
+}
+
+// Returns the values that differ between old and new, sorted by the order of their fields and the keys of maps.
+//
+// Structs and maps are compared per field or key, other values including slices are compared as a whole.
+// Fields tagged `secret:"true"` are reported with their values replaced by `cfg.SecretMask`.
+func Diff(old, new any) []Change {
+	return diff(reflect.ValueOf(old), reflect.ValueOf(new), "", false, []Change{})
+}
+
+// Dumps data to config file like `cfg.DumpAbs`, returns the values that differ from the previous content of the config file, see `cfg.Diff`.
+//
+// If config file is not present then data is compared against its zero value.
+func DumpAbsDiff(file string, data any) ([]Change, error) {
+	codec := CodecFor(file)
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return nil, Errors.NotAStruct
+	}
+
+	old := reflect.New(v.Type())
+	if bytes, err := os.ReadFile(file); err == nil && len(bytes) > 0 {
+		if _, _, err := migrate(bytes, old.Interface(), codec); err != nil {
+			return nil, err
+		}
+		if _, err := decryptSecrets(old.Interface()); err != nil {
+			return nil, err
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := DumpCodec(file, data, codec); err != nil {
+		return nil, err
+	}
+	return Diff(old.Interface(), data), nil
+}
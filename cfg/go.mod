@@ -1,3 +1,11 @@
 module github.com/HandyGold75/GOLib/cfg
 
 go 1.25.6
+
+require (
+	github.com/BurntSushi/toml v1.5.0
+	golang.org/x/term v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.40.0 // indirect
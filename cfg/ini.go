@@ -0,0 +1,450 @@
+package cfg
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	iniCodec struct{}
+
+	iniEntry struct{ key, value string }
+)
+
+func iniKey(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	} else if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+func iniIsText(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+}
+
+// Section fields are written as [section], all other fields as key = value.
+func iniIsSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return !iniIsText(t) && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map)
+}
+
+func iniQuote(s string, list bool) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\"\\\n\r;#") || (list && strings.Contains(s, ",")) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func iniFormat(v reflect.Value, list bool) (string, error) {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return iniQuote(string(text), list), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return iniQuote(v.String(), list), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if list {
+			return "", Errors.UnsupportedType
+		}
+		vals := []string{}
+		for i := range v.Len() {
+			val, err := iniFormat(v.Index(i), true)
+			if err != nil {
+				return "", err
+			}
+			vals = append(vals, val)
+		}
+		return strings.Join(vals, ", "), nil
+	}
+	return "", Errors.UnsupportedType
+}
+
+func iniWrite(v reflect.Value, section string) (string, error) {
+	keys, sections := "", ""
+	write := func(key string, value reflect.Value) error {
+		if value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+		if iniIsSection(value.Type()) {
+			if value.Kind() == reflect.Pointer && value.IsNil() {
+				return nil
+			}
+			name := key
+			if section != "" {
+				name = section + "." + key
+			}
+			sub, err := iniWrite(reflect.Indirect(value), name)
+			sections += sub
+			return err
+		}
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return nil
+		}
+		val, err := iniFormat(value, false)
+		if err != nil {
+			return fmt.Errorf("%w %v: %v", Errors.UnsupportedType, key, value.Type())
+		}
+		keys += key + " = " + val + "\n"
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if field.Anonymous && field.Tag.Get("json") == "" && reflect.Indirect(v.Field(i)).Kind() == reflect.Struct {
+				sub, err := iniWrite(reflect.Indirect(v.Field(i)), section)
+				if err != nil {
+					return "", err
+				}
+				keys += sub
+				continue
+			}
+			if key, ok := iniKey(field); ok {
+				if err := write(key, v.Field(i)); err != nil {
+					return "", err
+				}
+			}
+		}
+
+	case reflect.Map:
+		mapKeys := v.MapKeys()
+		slices.SortFunc(mapKeys, func(a, b reflect.Value) int { return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+		for _, k := range mapKeys {
+			if err := write(fmt.Sprint(k), v.MapIndex(k)); err != nil {
+				return "", err
+			}
+		}
+
+	default:
+		return "", Errors.NotAStruct
+	}
+
+	if section != "" && (keys != "" || sections == "") {
+		keys = "\n[" + section + "]\n" + keys
+	}
+	return keys + sections, nil
+}
+
+func iniSplitList(s string) ([]string, error) {
+	vals := []string{}
+	val, quoted, escaped := "", false, false
+	for _, char := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && char == '\\':
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case char == ',' && !quoted:
+			vals = append(vals, strings.TrimSpace(val))
+			val = ""
+			continue
+		}
+		val += string(char)
+	}
+	if quoted {
+		return vals, Errors.InvalidSyntax
+	}
+	if strings.TrimSpace(val) != "" || len(vals) > 0 {
+		vals = append(vals, strings.TrimSpace(val))
+	}
+	return vals, nil
+}
+
+// Parses s into v, lists are comma seperated and strings may be quoted.
+func parseValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && !iniIsText(v.Type()) {
+		vals, err := iniSplitList(s)
+		if err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Array && len(vals) > v.Len() {
+			return Errors.InvalidSyntax
+		} else if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(vals), len(vals)))
+		}
+		for i, val := range vals {
+			if err := parseValue(v.Index(i), val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if strings.HasPrefix(s, "\"") {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return Errors.InvalidSyntax
+		}
+		s = unquoted
+	}
+	if iniIsText(v.Type()) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return Errors.InvalidSyntax
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if d, dErr := time.ParseDuration(s); err != nil && dErr == nil && v.Type() == reflect.TypeFor[time.Duration]() {
+			i, err = int64(d), nil
+		}
+		if err != nil {
+			return Errors.InvalidSyntax
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return Errors.InvalidSyntax
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return Errors.InvalidSyntax
+		}
+		v.SetFloat(f)
+	default:
+		return Errors.UnsupportedType
+	}
+	return nil
+}
+
+func iniEntries(sections map[string][]iniEntry, section string) []iniEntry {
+	entries := []iniEntry{}
+	for name, sectionEntries := range sections {
+		if strings.EqualFold(name, section) {
+			entries = append(entries, sectionEntries...)
+		}
+	}
+	return entries
+}
+
+// Returns the names of the direct sub sections of section, for example "a" and "b" for "[section.a]" and "[section.b.c]".
+func iniSubSections(sections map[string][]iniEntry, section string) []string {
+	subs := []string{}
+	for name := range sections {
+		if len(name) <= len(section)+1 || !strings.EqualFold(name[:len(section)+1], section+".") {
+			continue
+		}
+		sub, _, _ := strings.Cut(name[len(section)+1:], ".")
+		if !slices.ContainsFunc(subs, func(s string) bool { return strings.EqualFold(s, sub) }) {
+			subs = append(subs, sub)
+		}
+	}
+	slices.Sort(subs)
+	return subs
+}
+
+func iniRead(v reflect.Value, section string, sections map[string][]iniEntry) error {
+	read := func(key string, value reflect.Value) error {
+		if iniIsSection(value.Type()) {
+			name := key
+			if section != "" {
+				name = section + "." + key
+			}
+			if len(iniEntries(sections, name)) == 0 && len(iniSubSections(sections, name)) == 0 {
+				if _, ok := sections[name]; !ok {
+					return nil
+				}
+			}
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					value.Set(reflect.New(value.Type().Elem()))
+				}
+				value = value.Elem()
+			}
+			return iniRead(value, name, sections)
+		}
+
+		for _, entry := range slices.Backward(iniEntries(sections, section)) {
+			if !strings.EqualFold(entry.key, key) {
+				continue
+			}
+			if err := parseValue(value, entry.value); err != nil {
+				return fmt.Errorf("%w for %v", err, key)
+			}
+			break
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if field.Anonymous && field.Tag.Get("json") == "" && (field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct)) {
+				value := v.Field(i)
+				if value.Kind() == reflect.Pointer {
+					if !value.CanSet() {
+						continue
+					}
+					if value.IsNil() {
+						value.Set(reflect.New(value.Type().Elem()))
+					}
+					value = value.Elem()
+				}
+				if err := iniRead(value, section, sections); err != nil {
+					return err
+				}
+				continue
+			}
+			if key, ok := iniKey(field); ok {
+				if err := read(key, v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return Errors.UnsupportedType
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		keys := []string{}
+		if iniIsSection(v.Type().Elem()) {
+			keys = iniSubSections(sections, section)
+		} else {
+			for _, entry := range iniEntries(sections, section) {
+				keys = append(keys, entry.key)
+			}
+		}
+		for _, key := range keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := read(key, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+
+	default:
+		return Errors.NotAStruct
+	}
+	return nil
+}
+
+// Decodes sections into a tree of maps, values are kept as strings.
+func iniTree(tree *map[string]any, sections map[string][]iniEntry) error {
+	if *tree == nil {
+		*tree = map[string]any{}
+	}
+	for name, entries := range sections {
+		m := *tree
+		if name != "" {
+			for part := range strings.SplitSeq(name, ".") {
+				sub, ok := m[part].(map[string]any)
+				if !ok {
+					sub = map[string]any{}
+					m[part] = sub
+				}
+				m = sub
+			}
+		}
+		for _, entry := range entries {
+			value := entry.value
+			if strings.HasPrefix(value, "\"") {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return Errors.InvalidSyntax
+				}
+				value = unquoted
+			}
+			m[entry.key] = value
+		}
+	}
+	return nil
+}
+
+func (iniCodec) Marshal(data any) ([]byte, error) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct && v.Kind() != reflect.Map {
+		return nil, Errors.NotAStruct
+	}
+	ini, err := iniWrite(v, "")
+	return []byte(strings.TrimPrefix(ini, "\n")), err
+}
+
+func (iniCodec) Unmarshal(bytes []byte, data any) error {
+	v := reflect.ValueOf(data)
+	tree, isTree := data.(*map[string]any)
+	if !isTree && (v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct) {
+		return Errors.NotAStruct
+	}
+
+	sections, section := map[string][]iniEntry{"": {}}, ""
+	for i, line := range strings.Split(strings.ReplaceAll(string(bytes), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := sections[section]; !ok {
+				sections[section] = []iniEntry{}
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%w on line %v", Errors.InvalidSyntax, i+1)
+		}
+		sections[section] = append(sections[section], iniEntry{key: strings.TrimSpace(key), value: strings.TrimSpace(value)})
+	}
+	if isTree {
+		return iniTree(tree, sections)
+	}
+	return iniRead(v.Elem(), "", sections)
+}
@@ -0,0 +1,193 @@
+package cfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Returns bytes with comments and trailing commas replaced by spaces, offsets and newlines are kept so positions in the result match bytes.
+//
+// Supports `// line` and `/* block */` comments and a trailing comma before a closing `}` or `]`.
+func stripJSONC(src []byte) []byte {
+	out := bytes.Clone(src)
+	inString := false
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			for i += 2; i < len(out) && !(out[i] == '*' && i+1 < len(out) && out[i+1] == '/'); i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			if i < len(out) {
+				out[i], out[i+1] = ' ', ' '
+				i++
+			}
+		}
+	}
+
+	inString = false
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := i + 1
+			for next < len(out) && strings.ContainsRune(" \t\r\n", rune(out[next])) {
+				next++
+			}
+			if next < len(out) && (out[next] == '}' || out[next] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+// Returns the line and column of offset in src, both starting at 1.
+func jsonPosition(src []byte, offset int64) (int, int) {
+	offset = min(max(offset, 0), int64(len(src)))
+	before := src[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len([]rune(string(before[bytes.LastIndexByte(before, '\n')+1:]))) + 1
+	return line, col
+}
+
+// Returns the type of the struct field json decodes key into, embedded structs are searched like encoding/json does.
+func jsonField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if sub, ok := jsonField(ft, key); ok {
+					return sub, true
+				}
+				continue
+			}
+		}
+		if name, ok := iniKey(field); ok && strings.EqualFold(name, key) {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// Walks the next value of dec as decoded into t, returns the dotted path and offset of the first key t has no field for.
+//
+// A nil t accepts any value, the offset of a key is the start of its opening quote.
+func unknownField(dec *json.Decoder, src []byte, t reflect.Type, path string) (string, int64, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (reflect.PointerTo(t).Implements(reflect.TypeFor[json.Unmarshaler]()) || t.Kind() == reflect.Interface) {
+		t = nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return "", 0, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			start := dec.InputOffset()
+			for start < int64(len(src)) && src[start] != '"' {
+				start++
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return "", 0, err
+			}
+			key, _ := tok.(string)
+
+			var elem reflect.Type
+			if t != nil && t.Kind() == reflect.Struct {
+				ft, ok := jsonField(t, key)
+				if !ok {
+					return path + key, start, nil
+				}
+				elem = ft
+			} else if t != nil && t.Kind() == reflect.Map {
+				elem = t.Elem()
+			}
+			if p, offset, err := unknownField(dec, src, elem, path+key+"."); err != nil || p != "" {
+				return p, offset, err
+			}
+		}
+		_, err = dec.Token()
+		return "", 0, err
+
+	case json.Delim('['):
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i := 0; dec.More(); i++ {
+			if p, offset, err := unknownField(dec, src, elem, fmt.Sprintf("%v%v.", path, i)); err != nil || p != "" {
+				return p, offset, err
+			}
+		}
+		_, err = dec.Token()
+		return "", 0, err
+	}
+	return "", 0, nil
+}
+
+// Decodes src into data, comments and trailing commas are allowed, see `cfg.Codecs`.
+//
+// When strict is set keys without a matching field are rejected with their position.
+func unmarshalJSONC(src []byte, data any, strict bool) error {
+	clean := stripJSONC(src)
+	if !strict {
+		return json.Unmarshal(clean, data)
+	}
+
+	key, offset, err := decodeStrict(clean, data)
+	if key == "" {
+		return err
+	}
+	line, col := jsonPosition(src, offset)
+	return fmt.Errorf("%w %v at line %v, column %v", Errors.UnknownField, key, line, col)
+}
+
+// Decodes clean json into data rejecting keys without a matching field, returns the dotted path and offset of the first unknown key.
+func decodeStrict(clean []byte, data any) (string, int64, error) {
+	dec := json.NewDecoder(bytes.NewReader(clean))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(data)
+	if err == nil || !strings.HasPrefix(err.Error(), "json: unknown field") {
+		return "", 0, err
+	}
+	key, offset, walkErr := unknownField(json.NewDecoder(bytes.NewReader(clean)), clean, reflect.TypeOf(data), "")
+	if walkErr != nil || key == "" {
+		return "", 0, err
+	}
+	return key, offset, err
+}
@@ -0,0 +1,170 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+type (
+	// Layer a config value was loaded from, see `cfg.LoadLayered`.
+	Layer struct {
+		// Name of the layer, one of "system", "user", "exec" or "env".
+		Name string
+		// Config file or env var the value was read from.
+		Source string
+	}
+)
+
+// Returns the system wide config dir, `/etc/golib` or `%ProgramData%/golib` on windows.
+func systemDir() string {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("ProgramData") + "/golib"
+	}
+	return "/etc/golib"
+}
+
+// Returns the first existing `<dir>/<name><ext>` for the registered codec extensions, .json first.
+func findFile(dir string, name string) string {
+	codecsMu.RLock()
+	exts := []string{}
+	for ext := range codecs {
+		exts = append(exts, ext)
+	}
+	codecsMu.RUnlock()
+	slices.Sort(exts)
+	if i := slices.Index(exts, ".json"); i > -1 {
+		exts = append([]string{".json"}, slices.Delete(exts, i, i+1)...)
+	}
+
+	for _, ext := range exts {
+		if file := CheckAbs(filepath.Join(dir, name+ext)); file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+func envName(parts ...string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(strings.Join(parts, "_")))
+}
+
+// Records the dotted path of every leaf in tree as coming from layer.
+func recordLayer(tree map[string]any, prefix string, layer Layer, layers map[string]Layer) {
+	for key, value := range tree {
+		if sub, ok := value.(map[string]any); ok {
+			recordLayer(sub, prefix+key+".", layer, layers)
+			continue
+		}
+		layers[prefix+key] = layer
+	}
+}
+
+// Sets fields of v from env vars named `<prefix>_<FIELD>`, nested fields are joined with "_", for example `GOLIB_MYAPP_DB_HOST`.
+func loadEnv(v reflect.Value, prefix string, path string, layers map[string]Layer) error {
+	for i := range v.NumField() {
+		field, value := v.Type().Field(i), v.Field(i)
+		key, ok := iniKey(field)
+		if !ok {
+			continue
+		}
+		name := envName(prefix, key)
+
+		if iniIsSection(field.Type) && reflect.Indirect(value).Kind() != reflect.Map && (field.Type.Kind() == reflect.Struct || field.Type.Elem().Kind() == reflect.Struct) {
+			target := value
+			if value.Kind() == reflect.Pointer {
+				target = reflect.New(field.Type.Elem())
+				if !value.IsNil() {
+					target.Elem().Set(value.Elem())
+				}
+				target = target.Elem()
+			}
+			found := len(layers)
+			if err := loadEnv(target, name, path+key+".", layers); err != nil {
+				return err
+			}
+			if value.Kind() == reflect.Pointer && len(layers) != found {
+				value.Set(target.Addr())
+			}
+			continue
+		}
+
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setText(value, env); err != nil {
+			return fmt.Errorf("%w %v: %v", Errors.InvalidValue, name, env)
+		}
+		layers[path+key] = Layer{Name: "env", Source: name}
+	}
+	return nil
+}
+
+// Loads data from multiple layers, later layers overwrite the values of earlier layers:
+//
+//	system: `<name>.<ext>` in `/etc/golib` (`%ProgramData%/golib` on windows).
+//	user:   `<name>.<ext>` in `./golib` relative to `os.UserConfigDir`.
+//	exec:   `<name>.<ext>` relative to `os.Executable`.
+//	env:    Env vars named `GOLIB_<NAME>_<FIELD>`, nested fields are joined with "_", for example `GOLIB_MYAPP_DB_HOST`.
+//
+// The ext of each file is any extension registered with a codec, see `cfg.CodecFor`, missing files are skipped and never created.
+// Structs and maps are merged deeply, other values including slices are replaced. Env values are parsed like default tags.
+//
+// Returns the layer each value was loaded from keyed by the dotted json path of the value, for example "db.host".
+// Values not present in any layer keep the value of data or their default tag, values set to zero by a layer are kept, see `cfg.ApplySchema`.
+func LoadLayered(name string, data any) (map[string]Layer, error) {
+	layers := map[string]Layer{}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return layers, Errors.NotAStruct
+	}
+
+	dirs := [][2]string{{"system", systemDir()}}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, [2]string{"user", dir + "/golib"})
+	}
+	if exec, err := os.Executable(); err == nil {
+		dirs = append(dirs, [2]string{"exec", filepath.Dir(exec)})
+	}
+
+	for _, dir := range dirs {
+		file := findFile(dir[1], name)
+		if file == "" {
+			continue
+		}
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			return layers, err
+		}
+		tree, _, err := migrate(bytes, data, CodecFor(file))
+		if err != nil {
+			return layers, fmt.Errorf("%v: %w", file, err)
+		}
+		recordLayer(tree, "", Layer{Name: dir[0], Source: file}, layers)
+	}
+
+	if err := loadEnv(v.Elem(), envName("golib", name), "", layers); err != nil {
+		return layers, err
+	}
+	if _, err := decryptSecrets(data); err != nil {
+		return layers, err
+	}
+	paths := []string{}
+	for path := range layers {
+		paths = append(paths, path)
+	}
+	if _, err := applyDefaults(v, "", pathSet(paths)); err != nil {
+		return layers, err
+	}
+	return layers, validate(v)
+}
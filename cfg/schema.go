@@ -0,0 +1,437 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Implemented by config structs that validate themselves, called after the validate tags are checked.
+	Validator interface {
+		Validate() error
+	}
+
+	// Upgrades a decoded config file from one version to the next, see `cfg.RegisterMigration`.
+	Migration func(tree map[string]any) error
+)
+
+var (
+	migrationsMu = sync.RWMutex{}
+	migrations   = map[reflect.Type]map[int]Migration{}
+)
+
+// Registers migration to upgrade config files of type T from version from to from+1.
+//
+// T needs an int field with json key "version", files without a version are treated as version 0.
+// Migrations receive the file decoded into maps, slices and scalars keyed by the json tags of T.
+func RegisterMigration[T any](from int, migration Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	t := reflect.TypeFor[T]()
+	if _, ok := migrations[t]; !ok {
+		migrations[t] = map[int]Migration{}
+	}
+	migrations[t][from] = migration
+}
+
+func migrationsFor(t reflect.Type) map[int]Migration {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return migrations[t]
+}
+
+// Returns the version the registered migrations upgrade to, -1 without migrations.
+func latestVersion(m map[int]Migration) int {
+	latest := -1
+	for from := range m {
+		latest = max(latest, from+1)
+	}
+	return latest
+}
+
+func versionField(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := range v.NumField() {
+		if key, ok := iniKey(v.Type().Field(i)); ok && strings.EqualFold(key, "version") && v.Field(i).CanInt() {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func treeVersion(tree map[string]any) (string, int, error) {
+	for key, value := range tree {
+		if !strings.EqualFold(key, "version") {
+			continue
+		}
+		switch v := value.(type) {
+		case int:
+			return key, v, nil
+		case int64:
+			return key, int(v), nil
+		case float64:
+			return key, int(v), nil
+		case string:
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return key, 0, fmt.Errorf("%w version: %v", Errors.InvalidValue, v)
+			}
+			return key, i, nil
+		}
+		return key, 0, fmt.Errorf("%w version: %v", Errors.InvalidValue, value)
+	}
+	return "version", 0, nil
+}
+
+// Decodes bytes into data running the registered migrations, returns true if bytes were migrated.
+//
+// Also returns the file decoded into a tree, used to find the keys missing from the file. The tree is nil if data is not a pointer to a struct or codec can not decode into a map.
+func migrate(bytes []byte, data any, codec Codec) (map[string]any, bool, error) {
+	if reflect.TypeOf(data) == nil || reflect.TypeOf(data).Kind() != reflect.Pointer || reflect.TypeOf(data).Elem().Kind() != reflect.Struct {
+		return nil, false, codec.Unmarshal(bytes, data)
+	}
+	m := migrationsFor(reflect.TypeOf(data).Elem())
+	if len(m) == 0 {
+		if err := codec.Unmarshal(bytes, data); err != nil {
+			return nil, false, err
+		}
+		tree := map[string]any{}
+		if err := codec.Unmarshal(bytes, &tree); err != nil {
+			return nil, false, nil
+		}
+		return tree, false, nil
+	}
+
+	tree := map[string]any{}
+	if err := codec.Unmarshal(bytes, &tree); err != nil {
+		return nil, false, err
+	}
+	key, version, err := treeVersion(tree)
+	if err != nil {
+		return nil, false, err
+	}
+	latest := latestVersion(m)
+	if version > latest {
+		return nil, false, fmt.Errorf("%w %v, latest is %v", Errors.UnknownVersion, version, latest)
+	}
+	migrated := false
+	for ; version < latest; version++ {
+		migration, ok := m[version]
+		if !ok {
+			return nil, false, fmt.Errorf("%w %v", Errors.MissingMigration, version)
+		}
+		if err := migration(tree); err != nil {
+			return nil, false, err
+		}
+		migrated = true
+	}
+	if !migrated {
+		return tree, false, codec.Unmarshal(bytes, data)
+	}
+	tree[key] = version
+	return tree, true, decodeTree(tree, data, codec)
+}
+
+// Decodes a migrated tree into data, INI trees hold their values as text so they are written back to INI and read with the typed INI reader.
+// Strict JSON rejects unknown keys of the migrated tree with their path, their position in the file is unknown after migrating.
+func decodeTree(tree map[string]any, data any, codec Codec) error {
+	switch c := codec.(type) {
+	case iniCodec:
+		bytes, err := codec.Marshal(tree)
+		if err != nil {
+			return err
+		}
+		return codec.Unmarshal(bytes, data)
+
+	case jsonCodec:
+		if !c.strict {
+			break
+		}
+		bytes, err := json.Marshal(tree)
+		if err != nil {
+			return err
+		}
+		key, _, err := decodeStrict(bytes, data)
+		if key != "" {
+			return fmt.Errorf("%w %v", Errors.UnknownField, key)
+		}
+		return err
+	}
+	return fromTree(tree, data)
+}
+
+// Returns a func reporting if a dotted json path is in paths or is the parent of a path in paths, paths are matched case insensitive like encoding/json does.
+func pathSet(paths []string) func(string) bool {
+	set := map[string]bool{}
+	for _, path := range paths {
+		path = strings.ToLower(path)
+		for i, c := range path {
+			if c == '.' {
+				set[path[:i]] = true
+			}
+		}
+		set[path] = true
+	}
+	return func(path string) bool { return set[strings.ToLower(path)] }
+}
+
+// Returns the dotted json paths of all keys in tree.
+func treePaths(tree map[string]any, prefix string, paths []string) []string {
+	for key, value := range tree {
+		paths = append(paths, prefix+key)
+		if sub, ok := value.(map[string]any); ok {
+			paths = treePaths(sub, prefix+key+".", paths)
+		}
+	}
+	return paths
+}
+
+func setDefault(v reflect.Value, def string) error {
+	if def == "" || !v.IsZero() {
+		return nil
+	}
+	return setText(v, def)
+}
+
+// Parses s into v like parseValue, maps are given as comma seperated key=value pairs.
+func setText(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Map {
+		m := reflect.MakeMap(v.Type())
+		vals, err := iniSplitList(s)
+		if err != nil {
+			return err
+		}
+		for _, val := range vals {
+			k, elem, ok := strings.Cut(val, "=")
+			if !ok {
+				return Errors.InvalidSyntax
+			}
+			key, value := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+			if err := parseValue(key, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			if err := parseValue(value, strings.TrimSpace(elem)); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+		return nil
+	}
+	return parseValue(v, s)
+}
+
+// Fills zero valued fields of v from their default tag, nested structs are filled recursively, returns true if a field was filled.
+//
+// Fields for which present reports the dotted json path are skipped so values set to zero on purpose are kept, a nil present fills every zero valued field.
+func applyDefaults(v reflect.Value, path string, present func(string) bool) (bool, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false, nil
+	}
+
+	filled := false
+	for i := range v.NumField() {
+		field, value := v.Type().Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, ok := iniKey(field)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			key = strings.TrimSuffix(path, ".")
+		} else if !ok {
+			key = path + field.Name
+		} else {
+			key = path + key
+		}
+
+		if def := field.Tag.Get("default"); def != "" && value.IsZero() && (present == nil || !present(key)) {
+			if err := setDefault(value, def); err != nil {
+				return filled, fmt.Errorf("%w default of %v: %v", Errors.InvalidValue, field.Name, def)
+			}
+			filled = true
+		}
+		if value.Kind() == reflect.Struct || (value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.Struct) {
+			sub := key + "."
+			if key == "" {
+				sub = ""
+			}
+			subFilled, err := applyDefaults(value, sub, present)
+			if err != nil {
+				return filled, err
+			}
+			filled = filled || subFilled
+		}
+	}
+	return filled, nil
+}
+
+func validateRule(field reflect.StructField, value reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	fail := func(detail string) error {
+		return fmt.Errorf("%w %v: %v", Errors.InvalidValue, field.Name, detail)
+	}
+
+	size := func() (float64, bool) {
+		switch value.Kind() {
+		case reflect.String:
+			return float64(len([]rune(value.String()))), true
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return float64(value.Len()), true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(value.Int()), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return float64(value.Uint()), true
+		case reflect.Float32, reflect.Float64:
+			return value.Float(), true
+		}
+		return 0, false
+	}
+
+	switch name {
+	case "required":
+		if value.IsZero() {
+			return fail("is required")
+		}
+
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		s, ok := size()
+		if err != nil || !ok {
+			return fmt.Errorf("%w validate tag of %v: %v", Errors.InvalidSyntax, field.Name, rule)
+		}
+		if name == "min" && s < limit {
+			return fail("must be at least " + arg)
+		} else if name == "max" && s > limit {
+			return fail("must be at most " + arg)
+		}
+
+	case "oneof":
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			return nil
+		}
+		val := fmt.Sprint(reflect.Indirect(value).Interface())
+		if !slices.Contains(strings.Fields(arg), val) {
+			return fail("must be one of " + arg)
+		}
+
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return fmt.Errorf("%w validate tag of %v: %v", Errors.InvalidSyntax, field.Name, rule)
+		}
+		if value.Kind() == reflect.String && value.String() != "" && !re.MatchString(value.String()) {
+			return fail("must match " + arg)
+		}
+
+	default:
+		return fmt.Errorf("%w validate tag of %v: %v", Errors.InvalidSyntax, field.Name, rule)
+	}
+	return nil
+}
+
+// Checks the validate tags of v and calls Validate on values implementing `cfg.Validator`, nested structs are checked recursively.
+func validate(v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := range v.NumField() {
+		field, value := v.Type().Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		rules := field.Tag.Get("validate")
+		for rules != "" {
+			rule := ""
+			if strings.HasPrefix(rules, "regex=") {
+				rule, rules = rules, ""
+			} else {
+				rule, rules, _ = strings.Cut(rules, ",")
+			}
+			if err := validateRule(field, value, rule); err != nil {
+				return err
+			}
+		}
+		if value.Kind() == reflect.Struct || (value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.Struct) {
+			if err := validate(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if v.CanAddr() {
+		if validator, ok := v.Addr().Interface().(Validator); ok {
+			return validator.Validate()
+		}
+	} else if validator, ok := v.Interface().(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// Decodes bytes into data, running migrations, decrypting secrets, filling defaults and validating, returns true if bytes should be rewritten.
+//
+// Defaults are only filled for keys missing from bytes, the file is rewritten to add them.
+func decode(bytes []byte, data any, codec Codec) (bool, error) {
+	tree, migrated, err := migrate(bytes, data, codec)
+	if err != nil {
+		return false, err
+	}
+	plain, err := decryptSecrets(data)
+	if err != nil {
+		return false, err
+	}
+	var present func(string) bool
+	if tree != nil {
+		present = pathSet(treePaths(tree, "", []string{}))
+	}
+	filled, err := applyDefaults(reflect.ValueOf(data), "", present)
+	if err != nil {
+		return false, err
+	}
+	return migrated || plain || filled, validate(reflect.ValueOf(data))
+}
+
+// Fills zero valued fields of data from their default tag and validates data. Loads only fill fields missing from the config file, dumps only validate.
+//
+// Default tags use the text of the value, lists and maps are comma seperated, for example `default:"a,b"` or `default:"k=v,k2=v2"`.
+//
+// Validate tags are comma seperated rules, regex must be the last rule:
+//
+//	required:  Value must not be zero.
+//	min=n:     Minimal value for numbers or minimal length for strings, slices and maps.
+//	max=n:     Maximal value for numbers or maximal length for strings, slices and maps.
+//	oneof=a b: Space seperated list of allowed values.
+//	regex=p:   Pattern non empty strings must match.
+//
+// Finally Validate is called if data implements `cfg.Validator`, also for nested structs.
+func ApplySchema(data any) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if _, err := applyDefaults(v, "", nil); err != nil {
+		return err
+	}
+	return validate(v)
+}
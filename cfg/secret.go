@@ -0,0 +1,272 @@
+package cfg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+const (
+	secretPrefix     = "golib-secret:v1:"
+	secretSaltSize   = 16
+	secretIterations = 100_000
+)
+
+var (
+	// Env var holding the passphrase for fields tagged `secret:"true"`.
+	SecretEnv = "GOLIB_SECRET"
+	// File holding the passphrase for fields tagged `secret:"true"`, when empty `./golib/secret.key` relative to `os.UserConfigDir` is used if present.
+	SecretKeyFile = ""
+
+	secretMu   = sync.Mutex{}
+	secretPass = ""
+	secretKeys = map[string][]byte{}
+	// Salt used for encrypting, taken from the first decrypted secret or generated once so the key is derived once per process.
+	secretSalt []byte
+	// Last plain text and stored value per secret field keyed by type and field path, so unchanged secrets keep their stored value.
+	secretValues = map[string][2]string{}
+)
+
+// Returns the passphrase from `cfg.SecretEnv`, `cfg.SecretKeyFile` or a terminal prompt, in that order. The passphrase is cached for the lifetime of the process.
+func secretPassphrase() (string, error) {
+	if secretPass != "" {
+		return secretPass, nil
+	}
+
+	pass := os.Getenv(SecretEnv)
+	if pass == "" {
+		file := SecretKeyFile
+		if dir, err := os.UserConfigDir(); file == "" && err == nil {
+			file = CheckAbs(dir + "/golib/secret.key")
+		}
+		if file != "" {
+			bytes, err := os.ReadFile(file)
+			if err != nil {
+				return "", err
+			}
+			pass = strings.TrimSpace(string(bytes))
+		} else if term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprint(os.Stderr, "Config passphrase: ")
+			bytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", err
+			}
+			pass = string(bytes)
+		}
+	}
+	if pass == "" {
+		return "", Errors.MissingKey
+	}
+	secretPass = pass
+	return pass, nil
+}
+
+func secretKey(salt []byte) ([]byte, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if key, ok := secretKeys[string(salt)]; ok {
+		return key, nil
+	}
+	pass, err := secretPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	key, err := pbkdf2.Key(sha256.New, pass, salt, secretIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	secretKeys[string(salt)] = key
+	return key, nil
+}
+
+func secretCipher(salt []byte) (cipher.AEAD, error) {
+	key, err := secretKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptionSalt() ([]byte, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if secretSalt == nil {
+		salt := make([]byte, secretSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		secretSalt = salt
+	}
+	return secretSalt, nil
+}
+
+func encryptSecret(plain string) (string, error) {
+	salt, err := encryptionSalt()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := secretCipher(salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(append(append([]byte{}, salt...), nonce...), nonce, []byte(plain), nil)
+	return secretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(s string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s, secretPrefix))
+	if err != nil || len(sealed) < secretSaltSize {
+		return "", Errors.InvalidSecret
+	}
+	salt := sealed[:secretSaltSize]
+	gcm, err := secretCipher(salt)
+	if err != nil {
+		return "", err
+	}
+	secretMu.Lock()
+	if secretSalt == nil {
+		secretSalt = bytes.Clone(salt)
+	}
+	secretMu.Unlock()
+	sealed = sealed[secretSaltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return "", Errors.InvalidSecret
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", Errors.InvalidSecret
+	}
+	return string(plain), nil
+}
+
+func hasSecrets(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := range t.NumField() {
+		if field := t.Field(i); field.IsExported() && (field.Tag.Get("secret") == "true" || hasSecrets(field.Type, seen)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Calls fn with the field path and value of every non empty string field tagged `secret:"true"` in v, replacing the field with the result.
+//
+// Pointers to structs are copied before descending when copyPtrs is set, so the caller's data is left untouched.
+func walkSecrets(v reflect.Value, path string, copyPtrs bool, fn func(string, string) (string, error)) error {
+	for i := range v.NumField() {
+		field, value := v.Type().Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			s, err := fn(path+field.Name, value.String())
+			if err != nil {
+				return fmt.Errorf("%w %v", err, field.Name)
+			}
+			value.SetString(s)
+			continue
+		}
+
+		if value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+			if copyPtrs {
+				cp := reflect.New(value.Type().Elem())
+				cp.Elem().Set(value.Elem())
+				value.Set(cp)
+			}
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Struct {
+			if err := walkSecrets(value, path+field.Name+".", copyPtrs, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Marshals data with codec, encrypting the values of fields tagged `secret:"true"` without modifying data.
+//
+// Secrets equal to the last loaded or dumped value of the same field keep their stored value, so dumping unchanged data gives the same file.
+func marshal(data any, codec Codec) ([]byte, error) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct || !hasSecrets(v.Type(), map[reflect.Type]bool{}) {
+		return codec.Marshal(data)
+	}
+
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	err := walkSecrets(cp.Elem(), v.Type().String()+".", true, func(path string, s string) (string, error) {
+		if strings.HasPrefix(s, secretPrefix) {
+			return s, nil
+		}
+		secretMu.Lock()
+		last, ok := secretValues[path]
+		secretMu.Unlock()
+		if ok && last[0] == s {
+			return last[1], nil
+		}
+		stored, err := encryptSecret(s)
+		if err != nil {
+			return "", err
+		}
+		secretMu.Lock()
+		secretValues[path] = [2]string{s, stored}
+		secretMu.Unlock()
+		return stored, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(cp.Interface())
+}
+
+// Decrypts the values of fields tagged `secret:"true"` in data, returns true if a value was stored as plain text.
+func decryptSecrets(data any) (bool, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct || !hasSecrets(v.Type(), map[reflect.Type]bool{}) {
+		return false, nil
+	}
+
+	plain := false
+	err := walkSecrets(v.Elem(), v.Elem().Type().String()+".", false, func(path string, s string) (string, error) {
+		if !strings.HasPrefix(s, secretPrefix) {
+			plain = true
+			return s, nil
+		}
+		decrypted, err := decryptSecret(s)
+		if err != nil {
+			return "", err
+		}
+		secretMu.Lock()
+		secretValues[path] = [2]string{decrypted, s}
+		secretMu.Unlock()
+		return decrypted, nil
+	})
+	return plain, err
+}
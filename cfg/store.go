@@ -0,0 +1,197 @@
+package cfg
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type (
+	// Store returned by `cfg.NewStore`, shares a config between goroutines and saves it to its config file.
+	Store[T any] struct {
+		mu      sync.RWMutex
+		saveMu  sync.Mutex
+		file    string
+		codec   Codec
+		data    T
+		delay   time.Duration
+		onSave  func(error)
+		timer   *time.Timer
+		pending bool
+		closed  bool
+	}
+)
+
+// Returns a copy of v, pointers, slices and maps are copied recursively so the copy shares no memory with v.
+//
+// Unexported fields are copied as is.
+func deepCopy(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return cp
+		}
+		cp.Set(reflect.New(v.Type().Elem()))
+		cp.Elem().Set(deepCopy(v.Elem()))
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return cp
+		}
+		cp.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := range v.Len() {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+	case reflect.Array:
+		for i := range v.Len() {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			return cp
+		}
+		cp.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for iter := v.MapRange(); iter.Next(); {
+			cp.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+		}
+
+	case reflect.Struct:
+		cp.Set(v)
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				cp.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+
+	case reflect.Interface:
+		if !v.IsNil() {
+			cp.Set(deepCopy(v.Elem()))
+		}
+
+	default:
+		cp.Set(v)
+	}
+	return cp
+}
+
+func copyOf[T any](data *T) T { return deepCopy(reflect.ValueOf(data).Elem()).Interface().(T) }
+
+// Returns a copy of the current data, changes to the copy are not stored, use `Store.Update` instead.
+func (s *Store[T]) Get() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyOf(&s.data)
+}
+
+// Calls fn with the data of the store while holding the write lock, fn must not keep references to data after returning.
+//
+// Schedules a save when autosave is enabled, see `Store.Autosave`.
+func (s *Store[T]) Update(fn func(data *T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.data)
+
+	if s.delay <= 0 || s.closed {
+		return
+	}
+	s.pending = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.delay, s.autosave)
+	} else {
+		s.timer.Reset(s.delay)
+	}
+}
+
+// Writes a copy of the current data to the config file using `cfg.DumpCodec`, updates are not blocked while writing.
+func (s *Store[T]) Save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	data := copyOf(&s.data)
+	s.pending = false
+	s.mu.Unlock()
+
+	return DumpCodec(s.file, &data, s.codec)
+}
+
+func (s *Store[T]) autosave() {
+	s.mu.RLock()
+	pending, onSave := s.pending, s.onSave
+	s.mu.RUnlock()
+	if !pending {
+		return
+	}
+	err := s.Save()
+	if onSave != nil {
+		onSave(err)
+	}
+}
+
+// Saves the config file delay after the last `Store.Update`, so a burst of updates is written once.
+//
+// onSave is called after every autosave with its error, or nil on success. A delay of 0 disables autosave.
+func (s *Store[T]) Autosave(delay time.Duration, onSave func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay, s.onSave = delay, onSave
+	if delay <= 0 && s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// Stops autosaving and saves pending updates, updates after Close are kept in memory only.
+func (s *Store[T]) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	pending := s.pending
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	if !pending {
+		return nil
+	}
+	return s.Save()
+}
+
+// Loads config file into a new store, see `cfg.Load` for the location of the config file.
+//
+// See `cfg.NewStoreAbs` for the behavior of the store.
+func NewStore[T any](name string, data T) (*Store[T], error) {
+	file, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreAbs(file+"/golib/"+name+".json", data)
+}
+
+// Loads config file into a new store, data is used as the initial value.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// The store is safe for concurrent use, `Store.Get` returns a copy and `Store.Update` modifies the data under a lock.
+// Changes are written by `Store.Save` or by autosave, see `Store.Autosave`, call `Store.Close` to write pending changes before exiting.
+//
+// The codec is chosen by the extension of file, see `cfg.CodecFor`.
+func NewStoreAbs[T any](file string, data T) (*Store[T], error) {
+	return NewStoreCodec(file, data, CodecFor(file))
+}
+
+// Loads config file into a new store using codec, saves use the same codec.
+//
+// Use `cfg.Codecs.StrictJSON` to reject unknown keys in the config file.
+//
+// See `cfg.NewStoreAbs` for the behavior of the store.
+func NewStoreCodec[T any](file string, data T, codec Codec) (*Store[T], error) {
+	s := &Store[T]{file: file, codec: codec, data: data}
+	if err := LoadCodec(file, &s.data, codec); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,146 @@
+package cfg
+
+import (
+	"hash/crc32"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// Watcher returned by `cfg.Watch`, reloads a config file into data when it changes.
+	//
+	// Reloads replace data while holding the write lock, hold the read lock while reading data or use `Watcher.Get`.
+	Watcher[T any] struct {
+		sync.RWMutex
+		file     string
+		codec    Codec
+		data     *T
+		onChange func(error)
+		stop     chan struct{}
+		done     chan struct{}
+		last     fileState
+	}
+
+	fileState struct {
+		modTime time.Time
+		size    int64
+		sum     uint32
+	}
+)
+
+var (
+	// Interval at which watched config files are polled for changes.
+	WatchInterval = time.Millisecond * 500
+	// Time a changed config file must be unchanged before it is reloaded, so a reload never reads a half written file.
+	WatchDebounce = time.Millisecond * 250
+)
+
+// The checksum catches edits that keep size and modification time, which can happen within the timestamp resolution of the file system.
+func statFile(file string) (fileState, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}, err
+	}
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size(), sum: crc32.ChecksumIEEE(bytes)}, nil
+}
+
+func (w *Watcher[T]) reload() error {
+	bytes, err := os.ReadFile(w.file)
+	if err != nil {
+		return err
+	}
+	fresh := new(T)
+	if _, err := decode(bytes, fresh, w.codec); err != nil {
+		return err
+	}
+	w.Lock()
+	*w.data = *fresh
+	w.Unlock()
+	return nil
+}
+
+func (w *Watcher[T]) watch() {
+	defer close(w.done)
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	last := w.last
+	pending, pendingSince := last, time.Time{}
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		state, err := statFile(w.file)
+		if err != nil || state == last {
+			// A missing file is skipped, editors and `cfg.Dump` may replace the file by renaming.
+			pending = last
+			continue
+		} else if state != pending {
+			pending, pendingSince = state, time.Now()
+			continue
+		} else if time.Since(pendingSince) < WatchDebounce {
+			continue
+		}
+
+		last = state
+		err = w.reload()
+		if w.onChange != nil {
+			w.onChange(err)
+		}
+	}
+}
+
+// Returns a copy of the current data.
+func (w *Watcher[T]) Get() T {
+	w.RLock()
+	defer w.RUnlock()
+	return *w.data
+}
+
+// Stops watching, onChange is not called after Close returns.
+func (w *Watcher[T]) Close() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+}
+
+// Loads config file into data and reloads data when the config file changes, see `cfg.Load` for the location of the config file.
+//
+// See `cfg.WatchAbs` for the reload behavior.
+func Watch[T any](name string, data *T, onChange func(error)) (*Watcher[T], error) {
+	file, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return WatchAbs(file+"/golib/"+name+".json", data, onChange)
+}
+
+// Loads config file into data and reloads data when the config file changes.
+//
+// If config file is not present then it tries creating it with content of data.
+//
+// The config file is polled every `cfg.WatchInterval`, a change is reloaded once the file is unchanged for `cfg.WatchDebounce`.
+// Reloads decode into a fresh value that replaces data under the lock of the returned watcher, fields missing from the file become zero or their default.
+// Reloads run migrations and validation like `cfg.LoadAbs` but never write the config file.
+// onChange is called after every reload with a nil error, or with the error of a failed reload in which case data keeps its previous value.
+func WatchAbs[T any](file string, data *T, onChange func(error)) (*Watcher[T], error) {
+	codec := CodecFor(file)
+	if err := LoadCodec(file, data, codec); err != nil {
+		return nil, err
+	}
+	w := &Watcher[T]{file: file, codec: codec, data: data, onChange: onChange, stop: make(chan struct{}), done: make(chan struct{})}
+	w.last, _ = statFile(file)
+	go w.watch()
+	return w, nil
+}
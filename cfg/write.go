@@ -0,0 +1,83 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	// Mode of written config files, configs may hold credentials so only the owner has access by default.
+	FileMode os.FileMode = 0o600
+	// Mode of created config directories.
+	DirMode os.FileMode = 0o755
+	// Number of backups kept when a config file is overwritten, named `<file>.1.bak` (newest) to `<file>.<Backups>.bak` (oldest).
+	Backups = 0
+)
+
+func rotateBackups(file string) error {
+	if Backups <= 0 {
+		return nil
+	}
+	bytes, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file + "." + strconv.Itoa(Backups) + ".bak"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := Backups - 1; i > 0; i-- {
+		if err := os.Rename(file+"."+strconv.Itoa(i)+".bak", file+"."+strconv.Itoa(i+1)+".bak"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.WriteFile(file+".1.bak", bytes, FileMode)
+}
+
+// Writes bytes to file atomically, bytes are written to a temporary file in the same dir which is synced and renamed over file.
+//
+// A crash mid write leaves either the old or the new file, never a partial one.
+func writeFile(file string, bytes []byte) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, DirMode); err != nil {
+		return err
+	}
+	if err := rotateBackups(file); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(FileMode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(bytes); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	// Sync the dir so the rename itself survives a crash, not supported on every platform.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}